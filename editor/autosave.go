@@ -0,0 +1,151 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AUTO_SAVE_INTERVAL is the time between two auto-saves of the modified buffers
+const AUTO_SAVE_INTERVAL = 30 * time.Second
+
+// autoSavePath returns the emacs style auto-save file of path, #name# in the
+// same directory.
+func autoSavePath(path string) string {
+	dir, name := filepath.Split(path)
+	return filepath.Join(dir, "#"+name+"#")
+}
+
+// StartAutoSave auto-saves the modified buffers every AUTO_SAVE_INTERVAL.
+func (e *Editor) StartAutoSave() {
+	e.AfterFunc(AUTO_SAVE_INTERVAL, func() {
+		e.AutoSave()
+		e.StartAutoSave()
+	})
+}
+
+// AutoSave writes the buffers changed since their last auto-save to their
+// auto-save files.
+func (e *Editor) AutoSave() {
+	for _, b := range e.modifiedBuffers() {
+		if b.autoSaved == b.changes {
+			continue
+		}
+		if err := b.writeAutoSave(); err != nil {
+			e.Minibuffer.SetMessage("Error auto-saving " + b.Name + ": " + err.Error())
+			continue
+		}
+		b.autoSaved = b.changes
+	}
+}
+
+// EmergencyAutoSave auto-saves every modified buffer when the editor crashes.
+// It returns the auto-save files written.
+func (e *Editor) EmergencyAutoSave() []string {
+	var written []string
+	for _, b := range e.modifiedBuffers() {
+		func() {
+			// the crash may have left a buffer broken, the others are still saved
+			defer func() {
+				recover()
+			}()
+			if b.writeAutoSave() == nil {
+				written = append(written, autoSavePath(b.Path))
+			}
+		}()
+	}
+	return written
+}
+
+func (b *Buffer) writeAutoSave() error {
+	content, err := encodeContent([]byte(b.text(0, b.length())), b.LineEnding, b.Charset)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(autoSavePath(b.Path), content, 0600)
+}
+
+// removeAutoSave deletes the auto-save file of b once it is saved.
+func (b *Buffer) removeAutoSave() error {
+	b.autoSaved = b.changes
+	err := os.Remove(autoSavePath(b.Path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// hasNewerAutoSave tells if path has an auto-save file more recent than itself.
+func hasNewerAutoSave(path string) bool {
+	auto, err := os.Stat(autoSavePath(path))
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err != nil || auto.ModTime().After(info.ModTime())
+}
+
+// RecoverFile visits path with the content of its auto-save file, after
+// confirmation. The buffer is left modified, saving it completes the recovery.
+// Unsaved changes of a buffer already visiting path are only discarded after
+// another confirmation.
+func (e *Editor) RecoverFile(path string) {
+	auto := autoSavePath(path)
+	if !hasNewerAutoSave(path) {
+		if _, err := os.Stat(auto); err != nil {
+			e.Minibuffer.SetMessage("No auto-save file for " + path)
+		} else {
+			e.Minibuffer.SetMessage("Auto-save file " + auto + " is not current")
+		}
+		return
+	}
+	if i := e.findBuffer(path); i >= 0 && e.OpenBuffers[i].Modified {
+		e.YesOrNoP(e.OpenBuffers[i].Name+" is modified; discard its changes?", func(yes bool) {
+			if yes {
+				e.recoverAutoSave(path)
+			}
+		})
+		return
+	}
+	e.recoverAutoSave(path)
+}
+
+func (e *Editor) recoverAutoSave(path string) {
+	auto := autoSavePath(path)
+	e.YesOrNoP("Recover auto save file "+auto+"?", func(yes bool) {
+		if !yes {
+			return
+		}
+		content, err := os.ReadFile(auto)
+		if err != nil {
+			e.Minibuffer.SetMessage("Error reading auto-save file: " + err.Error())
+			return
+		}
+		b := e.FindFile(path)
+		if b == nil {
+			return
+		}
+		content, _ = decodeCharset(content)
+		content, _ = decodeLineEndings(content)
+		b.replaceContent(string(content))
+		e.Minibuffer.SetMessage("Recovered " + path + ", save it to keep the recovered text")
+	})
+}
+
+// replaceContent replaces the whole text of b as one undo step.
+func (b *Buffer) replaceContent(text string) {
+	if b.readOnly() {
+		return
+	}
+	b.undo.BeginGroup()
+	b.moveTo(0)
+	if b.length() > 0 {
+		b.markPos = b.length()
+		b.markActive = true
+		b.deleteToMark()
+	}
+	b.Insert(text, true)
+	b.undo.EndGroup()
+	b.moveTo(0)
+}
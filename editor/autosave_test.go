@@ -0,0 +1,80 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAutoSave(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	auto := filepath.Join(dir, "#notes.txt#")
+	writeFile(t, path, "one\n")
+
+	e := CreateEditor()
+	b := e.FindFile(path)
+	e.AutoSave()
+	if _, err := os.Stat(auto); err == nil {
+		t.Fatalf("expected no auto-save for an unmodified buffer\n")
+	}
+
+	b.MoveEndFile()
+	b.Insert("two\n", true)
+	e.AutoSave()
+	if content, _ := os.ReadFile(auto); string(content) != "one\ntwo\n" {
+		t.Errorf("expected the auto-save to have the buffer text, found %q\n", content)
+	}
+
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(auto); err == nil {
+		t.Errorf("expected saving to delete the auto-save file\n")
+	}
+}
+
+func TestRecoverFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	writeFile(t, path, "one\n")
+	writeFile(t, filepath.Join(dir, "#notes.txt#"), "one\nlost\n")
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(path, old, old)
+
+	e := CreateEditor()
+	e.RecoverFile(path)
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	b := e.GetCurrentBuffer()
+	if b.Path != path || b.text(0, b.length()) != "one\nlost\n" || !b.Modified {
+		t.Errorf("expected the auto-saved text, found %q\n", b.text(0, b.length()))
+	}
+	b.Undo()
+	if b.text(0, b.length()) != "one\n" {
+		t.Errorf("expected the recovery to be undone in one step, found %q\n", b.text(0, b.length()))
+	}
+
+	b.Insert("edited\n", true)
+	e.RecoverFile(path)
+	if line := e.Minibuffer.GetLine(); !strings.Contains(line, "is modified") {
+		t.Fatalf("expected to be asked before discarding the changes, found %q\n", line)
+	}
+	e.Minibuffer.SetInput("no")
+	e.Minibuffer.ConfirmAction()
+	if b.text(0, b.length()) != "one\nedited\n" || e.Minibuffer.Focused {
+		t.Errorf("expected the changes to be kept, found %q\n", b.text(0, b.length()))
+	}
+	e.RecoverFile(path)
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	if b.text(0, b.length()) != "one\nlost\n" {
+		t.Errorf("expected the auto-saved text, found %q\n", b.text(0, b.length()))
+	}
+}
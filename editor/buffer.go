@@ -35,7 +35,29 @@ type Buffer struct {
 	killBuffer   []byte
 	ReadOnlyMode bool
 	Name         string
+	Mode         *Mode
 	undo         *UndoStack
+	Modified     bool // changed since the file was read or saved
+	changes      int  // number of changes, to tell if an auto-save is current
+	autoSaved    int  // changes when the buffer was last auto-saved
+
+	TabWidth         int
+	IndentWidth      int  // columns of one indentation level when indenting with spaces
+	IndentWithSpaces bool // indentation and TAB insert spaces instead of tabs
+
+	Path                   string // file visited by the buffer, empty for other buffers
+	Dir                    string // default directory of a buffer not visiting a file
+	dired                  *dired // listing shown by a directory buffer
+	errors                 *errorList
+	highlights             map[int][][2]int // byte ranges shown highlighted, by row
+	AutoRevertTail         bool             // follow the text appended to the file, like a log
+	disk                   diskState
+	locking                bool // lock the file while the buffer is modified
+	locked                 bool
+	LineEnding             string // line ending written on save: lf, crlf or cr
+	Charset                string // encoding written on save
+	TrimTrailingWhitespace bool
+	InsertFinalNewline     bool
 }
 
 func NewEmptyBuffer(parent *Editor) *Buffer {
@@ -45,19 +67,26 @@ func NewEmptyBuffer(parent *Editor) *Buffer {
 	return &Buffer{
 		parent:       parent,
 		Name:         "scratch",
+		Mode:         TextMode,
 		content:      buf,
 		ReadOnlyMode: false,
 		gapStart:     0,
 		gapEnd:       GAP_LEN,
 		undo:         NewUndo(UNDO_SIZE),
+		TabWidth:     TABSIZE,
+		IndentWidth:  TABSIZE,
+		LineEnding:   "lf",
+		Charset:      "utf-8",
 	}
 }
 
 func NewBuffer(parent *Editor, name string, content []byte, readOnly bool) *Buffer {
+	var b *Buffer
 	if readOnly {
-		return &Buffer{
+		b = &Buffer{
 			parent:       parent,
 			Name:         name,
+			Mode:         ModeForFile(name),
 			content:      content,
 			ReadOnlyMode: true,
 			undo:         NewUndo(0),
@@ -65,9 +94,10 @@ func NewBuffer(parent *Editor, name string, content []byte, readOnly bool) *Buff
 	} else {
 		buf := make([]byte, GAP_LEN, len(content)+GAP_LEN)
 		buf = append(buf, content...)
-		return &Buffer{
+		b = &Buffer{
 			parent:       parent,
 			Name:         name,
+			Mode:         ModeForFile(name),
 			content:      buf,
 			ReadOnlyMode: false,
 			gapStart:     0,
@@ -75,12 +105,52 @@ func NewBuffer(parent *Editor, name string, content []byte, readOnly bool) *Buff
 			undo:         NewUndo(UNDO_SIZE),
 		}
 	}
+
+	b.Path = name
+	b.LineEnding = "lf"
+	b.Charset = "utf-8"
+	// the content shows how it is indented, not how wide its tabs are meant
+	// to be: the tab width keeps its default
+	b.TabWidth = TABSIZE
+	b.IndentWidth = TABSIZE
+	if spaces, width, found := utils.DetectIndent(content); found {
+		b.IndentWithSpaces = spaces
+		if spaces {
+			b.IndentWidth = width
+		}
+	}
+	return b
+}
+
+// readOnly tells the user when the buffer cannot be modified.
+func (b *Buffer) readOnly() bool {
+	if b.ReadOnlyMode {
+		b.parent.Minibuffer.SetMessage("Buffer is read-only: " + b.Name)
+	}
+	return b.ReadOnlyMode
+}
+
+// changed records a change of the content.
+func (b *Buffer) changed() {
+	if !b.Modified {
+		if err := b.lock(); err != nil {
+			b.parent.Minibuffer.SetMessage(err.Error())
+		}
+	}
+	b.Modified = true
+	b.changes += 1
 }
 
 func (b *Buffer) GetBaseRow() int {
 	return b.baseRow
 }
 
+// Highlights returns the byte ranges of row to show highlighted, like the
+// matches of a search.
+func (b *Buffer) Highlights(row int) [][2]int {
+	return b.highlights[row]
+}
+
 func (b *Buffer) GetContent(count int, tabsize int) (string, int, Cursor, Mark) {
 	row := 0
 	col := 0
@@ -189,11 +259,14 @@ func (b *Buffer) GetContent(count int, tabsize int) (string, int, Cursor, Mark)
 }
 
 func (b *Buffer) Insert(str string, withUndo bool) {
+	if b.readOnly() {
+		return
+	}
 	if b.markActive {
 		b.deleteToMark()
 	}
 
-	if b.gapEnd-b.gapStart < GAP_THRESHOLD {
+	for b.gapEnd-b.gapStart < len(str)+GAP_THRESHOLD {
 		b.resizeGap()
 	}
 	for i, ch := range []byte(str) {
@@ -204,9 +277,15 @@ func (b *Buffer) Insert(str string, withUndo bool) {
 		forceNewEvent = true
 	}
 	if withUndo {
-		b.undo.EmitEvent(INSERT_EVENT, b.gapStart, "", forceNewEvent)
+		// one event per byte so that they merge and undo removes all of them
+		for i := 0; i < len(str); i++ {
+			b.undo.EmitEvent(INSERT_EVENT, b.gapStart+i, "", forceNewEvent && i == 0)
+		}
 	}
 	b.gapStart = b.gapStart + len(str)
+	if len(str) > 0 {
+		b.changed()
+	}
 	b.updateLinePosMem()
 }
 
@@ -224,10 +303,14 @@ func (b *Buffer) deleteToMark() {
 		}
 	}
 	b.ToggleMark()
+	b.changed()
 	b.updateLinePosMem()
 }
 
 func (b *Buffer) DeleteBefore() {
+	if b.readOnly() {
+		return
+	}
 	if b.markActive {
 		b.deleteToMark()
 		return
@@ -235,11 +318,15 @@ func (b *Buffer) DeleteBefore() {
 	if b.gapStart > 0 {
 		b.undo.EmitEvent(DELETE_EVENT, b.gapStart-1, string(b.content[b.gapStart-1]), false)
 		b.gapStart -= 1
+		b.changed()
 		b.updateLinePosMem()
 	}
 }
 
 func (b *Buffer) DeleteAfter(withUndo bool) {
+	if b.readOnly() {
+		return
+	}
 	if b.markActive {
 		b.deleteToMark()
 		return
@@ -249,11 +336,15 @@ func (b *Buffer) DeleteAfter(withUndo bool) {
 			b.undo.EmitEvent(DELETE_EVENT, b.gapStart, string(b.content[b.gapEnd]), false)
 		}
 		b.gapEnd += 1
+		b.changed()
 		b.updateLinePosMem()
 	}
 }
 
 func (b *Buffer) DeleteWordBefore() {
+	if b.readOnly() {
+		return
+	}
 	if b.gapStart == 0 {
 		return
 	}
@@ -261,6 +352,7 @@ func (b *Buffer) DeleteWordBefore() {
 		b.deleteToMark()
 		return
 	}
+	b.changed()
 	if b.content[b.gapStart-1] == '\n' {
 		b.gapStart -= 1
 		b.undo.EmitEvent(DELETE_EVENT, b.gapStart, string(b.content[b.gapStart]), false)
@@ -296,19 +388,42 @@ func (b *Buffer) DeleteWordBefore() {
 	b.updateLinePosMem()
 }
 
-func (b *Buffer) DeleteToEnd() {
+// DeleteToEnd kills the rest of the current line. With a count greater than
+// one it kills count lines forward, including their newlines. A count of zero
+// kills back to the start of the line, a negative one also kills the -count
+// lines before it.
+func (b *Buffer) DeleteToEnd(count int) {
+	if b.readOnly() {
+		return
+	}
 	if b.markActive {
 		b.ToggleMark()
 	}
 	b.killBuffer = b.killBuffer[0:0]
-	if b.gapEnd < len(b.content) {
-		for i := b.gapEnd; i <= len(b.content); i++ {
-			if i == len(b.content) || b.content[i] == '\n' {
+	if count <= 0 {
+		start := b.rowStart(max(b.CurrentRow()+count, 0))
+		b.killBuffer = append(b.killBuffer, b.content[start:b.gapStart]...)
+		for b.gapStart > start {
+			b.undo.EmitEvent(DELETE_EVENT, b.gapStart-1, string(b.content[b.gapStart-1]), false)
+			b.gapStart -= 1
+			b.changed()
+		}
+		b.updateLinePosMem()
+		return
+	}
+	lines := 0
+	for i := b.gapEnd; i < len(b.content); i++ {
+		if b.content[i] == '\n' && count <= 1 {
+			break
+		}
+		b.killBuffer = append(b.killBuffer, b.content[i])
+		b.gapEnd += 1
+		b.changed()
+		b.undo.EmitEvent(DELETE_EVENT, b.gapStart, string(b.content[i]), false)
+		if b.content[i] == '\n' {
+			lines += 1
+			if lines == count {
 				break
-			} else {
-				b.killBuffer = append(b.killBuffer, b.content[i])
-				b.gapEnd += 1
-				b.undo.EmitEvent(DELETE_EVENT, b.gapStart, string(b.content[i]), false)
 			}
 		}
 	}
@@ -334,6 +449,9 @@ func (b *Buffer) Copy() {
 }
 
 func (b *Buffer) Cut() {
+	if b.readOnly() {
+		return
+	}
 	b.killBuffer = b.killBuffer[0:0]
 	if !b.markActive {
 		return
@@ -357,6 +475,7 @@ func (b *Buffer) Cut() {
 		}
 	}
 	b.ToggleMark()
+	b.changed()
 	b.parent.Minibuffer.SetMessage("Cut region")
 }
 
@@ -624,7 +743,121 @@ func (b *Buffer) MoveEndFile() {
 	b.updateLinePosMem()
 }
 
+// byteAt returns the byte at logical position pos, skipping over the gap.
+func (b *Buffer) byteAt(pos int) byte {
+	if pos < b.gapStart {
+		return b.content[pos]
+	}
+	return b.content[pos+b.gapEnd-b.gapStart]
+}
+
+// length returns the number of bytes in the buffer, without the gap.
+func (b *Buffer) length() int {
+	return len(b.content) - (b.gapEnd - b.gapStart)
+}
+
+// moveTo moves the cursor to logical position pos.
+func (b *Buffer) moveTo(pos int) {
+	if pos < b.gapStart {
+		b.shiftGapLeft(b.gapStart - pos)
+	} else {
+		b.shiftGapRight(pos - b.gapStart)
+	}
+	b.updateLinePosMem()
+}
+
+// rowOf returns the row of logical position pos.
+func (b *Buffer) rowOf(pos int) int {
+	row := 0
+	for i := 0; i < pos; i++ {
+		if b.byteAt(i) == '\n' {
+			row += 1
+		}
+	}
+	return row
+}
+
+func (b *Buffer) LineCount() int {
+	return b.rowOf(b.length()) + 1
+}
+
+func (b *Buffer) CurrentRow() int {
+	return b.rowOf(b.gapStart)
+}
+
+// RegionRows returns the first and last row touched by the active region.
+// Like emacs, a region ending at the start of a line does not include that line.
+func (b *Buffer) RegionRows() (int, int, bool) {
+	if !b.markActive {
+		return 0, 0, false
+	}
+	start, end := b.markPos, b.gapStart
+	if start > end {
+		start, end = end, start
+	}
+	startRow, endRow := b.rowOf(start), b.rowOf(end)
+	if endRow > startRow && b.byteAt(end-1) == '\n' {
+		endRow -= 1
+	}
+	return startRow, endRow, true
+}
+
+// GotoLine moves the cursor to the start of row, or to the end of the buffer
+// if row is past the last line.
+func (b *Buffer) GotoLine(row int) {
+	b.moveTo(b.rowStart(row))
+}
+
+// rowStart returns the logical position of the start of row.
+func (b *Buffer) rowStart(row int) int {
+	pos := 0
+	for current := 0; current < row && pos < b.length(); pos++ {
+		if b.byteAt(pos) == '\n' {
+			current += 1
+		}
+	}
+	return pos
+}
+
+func (b *Buffer) lineStart(pos int) int {
+	for pos > 0 && b.byteAt(pos-1) != '\n' {
+		pos -= 1
+	}
+	return pos
+}
+
+func (b *Buffer) lineEnd(pos int) int {
+	for pos < b.length() && b.byteAt(pos) != '\n' {
+		pos += 1
+	}
+	return pos
+}
+
+// text returns the content between logical positions start and end.
+func (b *Buffer) text(start int, end int) string {
+	res := make([]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		res = append(res, b.byteAt(i))
+	}
+	return string(res)
+}
+
+// UndoTimes undoes the last count changes. Undoing cannot go forward, a count
+// below one is refused.
+func (b *Buffer) UndoTimes(count int) {
+	if count < 1 {
+		b.parent.Minibuffer.SetMessage("Undo needs a positive count")
+		return
+	}
+	for i := 0; i < count; i++ {
+		b.Undo()
+	}
+}
+
 func (b *Buffer) Undo() {
+	if b.readOnly() {
+		return
+	}
 	if b.markActive {
 		b.ToggleMark()
 	}
@@ -632,28 +865,35 @@ func (b *Buffer) Undo() {
 	if err != nil {
 		b.parent.Minibuffer.SetMessage(err.Error())
 		return
-	} else {
-		switch ev.Type {
-		case INSERT_EVENT:
-			if b.gapStart > ev.Pos {
-				b.shiftGapLeft(b.gapStart - ev.Pos)
-				for i := 0; i < ev.NumChar; i++ {
-					b.DeleteAfter(false)
-				}
-			} else {
-				b.shiftGapRight(ev.Pos - b.gapStart)
-				for i := 0; i < ev.NumChar; i++ {
-					b.DeleteAfter(false)
-				}
+	}
+	b.applyUndoEvent(ev)
+	for ev.Group != 0 && b.undo.HeadGroup() == ev.Group {
+		ev, _ = b.undo.PopUndoEvent()
+		b.applyUndoEvent(ev)
+	}
+}
+
+func (b *Buffer) applyUndoEvent(ev *UndoEvent) {
+	switch ev.Type {
+	case INSERT_EVENT:
+		if b.gapStart > ev.Pos {
+			b.shiftGapLeft(b.gapStart - ev.Pos)
+			for i := 0; i < ev.NumChar; i++ {
+				b.DeleteAfter(false)
 			}
-		case DELETE_EVENT:
-			if b.gapStart > ev.Pos {
-				b.shiftGapLeft(b.gapStart - ev.Pos)
-				b.Insert(ev.StoredText, false)
-			} else {
-				b.shiftGapRight(ev.Pos - b.gapStart)
-				b.Insert(ev.StoredText, false)
+		} else {
+			b.shiftGapRight(ev.Pos - b.gapStart)
+			for i := 0; i < ev.NumChar; i++ {
+				b.DeleteAfter(false)
 			}
 		}
+	case DELETE_EVENT:
+		if b.gapStart > ev.Pos {
+			b.shiftGapLeft(b.gapStart - ev.Pos)
+			b.Insert(ev.StoredText, false)
+		} else {
+			b.shiftGapRight(ev.Pos - b.gapStart)
+			b.Insert(ev.StoredText, false)
+		}
 	}
 }
@@ -0,0 +1,46 @@
+package editor
+
+import "testing"
+
+func TestDeleteToEnd(t *testing.T) {
+	testData := []struct {
+		count    int
+		expected string
+		killed   string
+	}{
+		{1, "one\ntw\nthree", "o"},
+		{2, "one\ntw", "o\nthree"},
+		{0, "one\no\nthree", "tw"},
+		{-1, "o\nthree", "one\ntw"},
+		{-5, "o\nthree", "one\ntw"},
+	}
+
+	for _, data := range testData {
+		content := "one\ntwo\nthree"
+		b := newTestBuffer(t, "a.txt", content)
+		b.moveTo(6)
+		b.DeleteToEnd(data.count)
+		if res := b.text(0, b.length()); res != data.expected || string(b.killBuffer) != data.killed {
+			t.Errorf("%d: expected (%q, %q), found (%q, %q)\n", data.count, data.expected, data.killed, res, b.killBuffer)
+		}
+		b.Undo()
+		if res := b.text(0, b.length()); res != content {
+			t.Errorf("%d: undo left %q\n", data.count, res)
+		}
+	}
+}
+
+func TestUndoTimes(t *testing.T) {
+	b := newTestBuffer(t, "a.txt", "")
+	b.Insert("a", true)
+	b.Newline()
+	b.Insert("b", true)
+	b.UndoTimes(-1)
+	if res := b.text(0, b.length()); res != "a\nb" || b.parent.Minibuffer.GetLine() != "Undo needs a positive count" {
+		t.Errorf("expected a negative count to be refused, found %q\n", res)
+	}
+	b.UndoTimes(2)
+	if res := b.text(0, b.length()); res != "a" {
+		t.Errorf("expected two changes to be undone, found %q\n", res)
+	}
+}
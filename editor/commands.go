@@ -0,0 +1,193 @@
+package editor
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Command is an editor command that can be invoked by name with M-x.
+// count is the numeric prefix argument, 1 when none was given.
+type Command func(e *Editor, count int)
+
+func (e *Editor) registerCommands() {
+	e.RegisterCommand("view-echo-area-messages", func(e *Editor, count int) {
+		e.ShowMessages()
+	})
+	e.RegisterCommand("save-some-buffers", func(e *Editor, count int) {
+		e.SaveSomeBuffers()
+	})
+	e.RegisterCommand("recover-file", func(e *Editor, count int) {
+		e.ReadFileName("Recover file: ", e.RecoverFile)
+	})
+	e.RegisterCommand("revert-buffer", func(e *Editor, count int) {
+		b := e.GetCurrentBuffer()
+		if b.dired != nil {
+			if err := e.diredRefresh(b); err != nil {
+				e.Minibuffer.SetMessage("Error reading directory: " + err.Error())
+			}
+			return
+		}
+		e.YesOrNoP("Revert buffer from file "+b.Path+"?", func(yes bool) {
+			if yes {
+				e.RevertBuffer(b)
+			}
+		})
+	})
+	e.RegisterCommand("auto-revert-tail-mode", func(e *Editor, count int) {
+		b := e.GetCurrentBuffer()
+		b.AutoRevertTail = !b.AutoRevertTail
+		if b.AutoRevertTail {
+			e.Minibuffer.SetMessage("Following the end of " + b.Name)
+		} else {
+			e.Minibuffer.SetMessage("Stopped following " + b.Name)
+		}
+	})
+	e.RegisterCommand("recentf-open", func(e *Editor, count int) {
+		e.RecentfOpen()
+	})
+	e.registerDiredCommands()
+	e.RegisterCommand("compile", func(e *Editor, count int) {
+		e.Compile()
+	})
+	e.RegisterCommand("recompile", func(e *Editor, count int) {
+		e.Recompile()
+	})
+	e.RegisterCommand("kill-compilation", func(e *Editor, count int) {
+		e.KillCompilation()
+	})
+	e.RegisterCommand("compile-goto-error", func(e *Editor, count int) {
+		e.GotoError()
+	})
+	e.RegisterCommand("next-error", func(e *Editor, count int) {
+		e.NextError(count)
+	})
+	e.RegisterCommand("previous-error", func(e *Editor, count int) {
+		e.NextError(-count)
+	})
+	e.RegisterCommand("grep", func(e *Editor, count int) {
+		e.Grep()
+	})
+	e.RegisterCommand("rgrep", func(e *Editor, count int) {
+		e.Rgrep()
+	})
+	e.RegisterCommand("grep-rerun", func(e *Editor, count int) {
+		e.GrepRerun()
+	})
+	e.RegisterCommand("shell-command", func(e *Editor, count int) {
+		e.ShellCommand()
+	})
+	e.RegisterCommand("shell-command-on-region", func(e *Editor, count int) {
+		// a prefix argument replaces the region
+		e.ShellCommandOnRegion(e.PrefixArg)
+	})
+	e.RegisterCommand("format-buffer", func(e *Editor, count int) {
+		b := e.GetCurrentBuffer()
+		if b.readOnly() {
+			return
+		}
+		if e.Config.Formatters[b.Mode.Name] == "" {
+			e.Minibuffer.SetMessage("No formatter for " + b.Mode.Name + " mode")
+		} else if err := b.format(); err != nil {
+			e.Minibuffer.SetMessage(err.Error())
+		}
+	})
+	e.RegisterCommand("indent-region", func(e *Editor, count int) {
+		e.GetCurrentBuffer().IndentRegion()
+	})
+	e.RegisterCommand("indent-rigidly", func(e *Editor, count int) {
+		e.GetCurrentBuffer().IndentRigidly(count)
+	})
+	e.RegisterCommand("tabify", func(e *Editor, count int) {
+		e.GetCurrentBuffer().Tabify()
+	})
+	e.RegisterCommand("untabify", func(e *Editor, count int) {
+		e.GetCurrentBuffer().Untabify()
+	})
+	e.RegisterCommand("set-tab-width", func(e *Editor, count int) {
+		e.ReadMinibuffer("Tab width: ", func(input string) {
+			width, err := strconv.Atoi(input)
+			if err != nil || width < 1 {
+				e.Minibuffer.SetMessage("Invalid tab width " + input)
+				return
+			}
+			e.GetCurrentBuffer().TabWidth = width
+		})
+	})
+	e.RegisterCommand("set-line-ending", func(e *Editor, count int) {
+		e.ReadMinibuffer("Line ending (lf, crlf, cr): ", func(input string) {
+			input = strings.ToLower(input)
+			switch input {
+			case "lf", "crlf", "cr":
+				b := e.GetCurrentBuffer()
+				if b.LineEnding != input {
+					b.LineEnding = input
+					b.changed()
+				}
+				e.Minibuffer.SetMessage("Line ending set to " + strings.ToUpper(input) + ", save to convert the file")
+			default:
+				e.Minibuffer.SetMessage("Invalid line ending " + input)
+			}
+		})
+	})
+	e.RegisterCommand("set-buffer-file-coding-system", func(e *Editor, count int) {
+		e.ReadMinibuffer("Coding system for saving file: ", func(input string) {
+			charset, lineEnding, ok := parseCodingSystem(input)
+			if !ok {
+				e.Minibuffer.SetMessage("Invalid coding system " + input)
+				return
+			}
+			b := e.GetCurrentBuffer()
+			if lineEnding == "" {
+				lineEnding = b.LineEnding
+			}
+			if b.Charset != charset || b.LineEnding != lineEnding {
+				b.Charset, b.LineEnding = charset, lineEnding
+				b.changed()
+			}
+			e.Minibuffer.SetMessage("Coding system set to " + charset + ", save to convert the file")
+		})
+	})
+	e.RegisterCommand("indent-tabs-mode", func(e *Editor, count int) {
+		b := e.GetCurrentBuffer()
+		b.IndentWithSpaces = !b.IndentWithSpaces
+		if b.IndentWithSpaces {
+			e.Minibuffer.SetMessage("Indenting with spaces")
+		} else {
+			e.Minibuffer.SetMessage("Indenting with tabs")
+		}
+	})
+}
+
+func (e *Editor) RegisterCommand(name string, cmd Command) {
+	e.commands[name] = cmd
+}
+
+// CommandNames returns the names of all registered commands, sorted.
+func (e *Editor) CommandNames() []string {
+	names := make([]string, 0, len(e.commands))
+	for name := range e.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *Editor) ExecuteExtendedCommand(count int) {
+	prefixArg := e.PrefixArg
+	e.CompletingRead("M-x ", e.CommandNames, HISTORY_COMMANDS, func(name string) {
+		e.PrefixArg = prefixArg
+		e.ExecuteCommand(name, count)
+		e.PrefixArg = false
+	})
+}
+
+// ExecuteCommand runs the command registered as name.
+func (e *Editor) ExecuteCommand(name string, count int) {
+	cmd, found := e.commands[name]
+	if !found {
+		e.Minibuffer.SetMessage("[No match] " + name)
+		return
+	}
+	cmd(e, count)
+}
@@ -0,0 +1,169 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	COMPILATION_BUFFER = "*compilation*"
+	COMPILE_COMMAND    = "go build ./..."
+)
+
+var CompilationMode = &Mode{
+	Name: "compilation",
+	Keys: map[string]string{
+		"RET": "compile-goto-error",
+		"g":   "recompile",
+	},
+}
+
+// compilation is the command running, or last run, in *compilation*.
+type compilation struct {
+	command string
+	dir     string
+	process *os.Process // nil once the command exited
+}
+
+// Compile prompts for a command, the last one by default, and runs it.
+func (e *Editor) Compile() {
+	command := COMPILE_COMMAND
+	if items := e.History.Items(HISTORY_COMPILE); len(items) > 0 {
+		command = items[len(items)-1]
+	}
+	e.readMinibuffer("Compile command: ", command, nil, HISTORY_COMPILE, func(command string) {
+		e.startCompilation(command, expandFileName(e.defaultDirectory()))
+	})
+}
+
+// Recompile runs the last compilation again in the same directory.
+func (e *Editor) Recompile() {
+	if e.compilation == nil {
+		e.Compile()
+		return
+	}
+	e.startCompilation(e.compilation.command, e.compilation.dir)
+}
+
+// KillCompilation stops the running compilation.
+func (e *Editor) KillCompilation() {
+	if e.compilation == nil || e.compilation.process == nil {
+		e.Minibuffer.SetMessage("No compilation running")
+		return
+	}
+	killProcess(e.compilation.process)
+}
+
+// startCompilation runs command with sh -c in dir, asking first to kill a
+// running compilation. Its output is streamed in *compilation*, where the
+// file:line: locations are collected for next-error.
+func (e *Editor) startCompilation(command string, dir string) {
+	if e.compilation != nil && e.compilation.process != nil {
+		e.YOrNP("A compilation process is running; kill it?", func(yes bool) {
+			if yes && e.compilation.process != nil {
+				killProcess(e.compilation.process)
+				e.compilation.process = nil
+				e.startCompilation(command, dir)
+			}
+		})
+		return
+	}
+
+	b := e.specialBuffer(COMPILATION_BUFFER)
+	b.Mode = CompilationMode
+	b.Dir = dir
+	b.errors = newErrorList(dir)
+	e.errorBuffer = b
+	b.setText("-*- mode: compilation; default-directory: \"" + abbreviateFileName(dir) + "/\" -*-\n" +
+		"Compilation started at " + time.Now().Format(time.ANSIC) + "\n\n" + command + "\n")
+	b.errors.row = b.LineCount() - 1
+	e.showBuffer(b)
+
+	c := &compilation{command: command, dir: dir}
+	e.compilation = c
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	err := e.startProcess(cmd, func(line string) {
+		if e.compilation == c {
+			e.compilationOutput(b, line)
+		}
+	}, func(err error) {
+		if e.compilation == c {
+			c.process = nil
+			e.compilationFinished(b, err)
+		}
+	})
+	if err != nil {
+		b.appendText("\nError running command: " + err.Error() + "\n")
+		return
+	}
+	c.process = cmd.Process
+}
+
+// startProcess starts cmd and calls onLine with each line of its output,
+// standard error included, then onExit with the result of the command. Both
+// are posted to the UI goroutine. The command runs in its own process group,
+// which killProcess stops.
+func (e *Editor) startProcess(cmd *exec.Cmd, onLine func(line string), onExit func(err error)) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stdout, cmd.Stderr = w, w
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		r.Close()
+		return err
+	}
+
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				e.Post(func() { onLine(line) })
+			}
+			if err != nil {
+				break
+			}
+		}
+		r.Close()
+		err := cmd.Wait()
+		e.Post(func() { onExit(err) })
+	}()
+	return nil
+}
+
+// killProcess kills the process group of p, so that the programs started by a
+// shell stop with it and release the output pipe.
+func killProcess(p *os.Process) {
+	syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
+
+// compilationOutput appends a line of output, recording its location.
+func (e *Editor) compilationOutput(b *Buffer, line string) {
+	b.errors.appendLine(b, line)
+}
+
+func (e *Editor) compilationFinished(b *Buffer, err error) {
+	status := "finished"
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() < 0 {
+			status = "killed"
+		} else {
+			status = "exited abnormally with code " + strconv.Itoa(exitErr.ExitCode())
+		}
+	} else if err != nil {
+		status = "failed: " + err.Error()
+	}
+	b.appendText("\nCompilation " + status + " at " + time.Now().Format(time.ANSIC) + "\n")
+	e.Minibuffer.SetMessage("Compilation " + status)
+}
@@ -0,0 +1,85 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runEvents runs the posted functions until done returns true.
+func runEvents(t *testing.T, e *Editor, done func() bool) {
+	timeout := time.After(5 * time.Second)
+	for !done() {
+		select {
+		case fn := <-e.Events():
+			fn()
+		case <-timeout:
+			t.Fatalf("timed out waiting for events\n")
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {\n\tfoo()\n}\n")
+	writeFile(t, filepath.Join(dir, "util.c"), "int x;\n")
+
+	e := CreateEditor()
+	e.startCompilation("echo './main.go:4:2: undefined: foo'; echo '    main_test.go:9: failed'; echo 'util.c:1:5: error: x'; exit 1", dir)
+	b := e.GetCurrentBuffer()
+	runEvents(t, e, func() bool { return e.compilation.process == nil })
+
+	if text := b.text(0, b.length()); !strings.Contains(text, "exited abnormally with code 1") {
+		t.Errorf("expected the exit status, found %q\n", text)
+	}
+	if len(b.errors.locations) != 3 {
+		t.Fatalf("expected 3 locations, found %v\n", b.errors.locations)
+	}
+
+	e.NextError(1)
+	target := e.GetCurrentBuffer()
+	if target.Path != filepath.Join(dir, "main.go") || target.CurrentRow() != 3 || target.gapStart != target.rowStart(3)+1 {
+		t.Errorf("expected main.go at 4:2, found %s at %d\n", target.Path, target.gapStart)
+	}
+	e.NextError(1)
+	if !strings.HasPrefix(e.Minibuffer.GetLine(), "Cannot find") {
+		t.Errorf("expected the missing test file to be reported, found %q\n", e.Minibuffer.GetLine())
+	}
+	e.NextError(1)
+	if e.GetCurrentBuffer().Path != filepath.Join(dir, "util.c") {
+		t.Errorf("expected util.c, found %s\n", e.GetCurrentBuffer().Path)
+	}
+	e.NextError(1)
+	if e.Minibuffer.GetLine() != "Moved past last error" {
+		t.Errorf("expected the end of the errors, found %q\n", e.Minibuffer.GetLine())
+	}
+	if files := e.recentFiles(); len(files) != 0 {
+		t.Errorf("expected the jumps not to be recorded as recent files, found %v\n", files)
+	}
+	e.NextError(-2)
+	if e.GetCurrentBuffer() != target {
+		t.Errorf("expected previous-error to return to main.go\n")
+	}
+
+	e.showBuffer(b)
+	b.GotoLine(b.errors.locations[2].row)
+	e.GotoError()
+	if e.GetCurrentBuffer().Path != filepath.Join(dir, "util.c") {
+		t.Errorf("expected RET to visit util.c\n")
+	}
+}
+
+func TestKillCompilation(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	e := CreateEditor()
+	// sleep is a grandchild, which must be killed for the output to end
+	e.startCompilation("sleep 30 | cat", t.TempDir())
+	b := e.GetCurrentBuffer()
+	e.KillCompilation()
+	runEvents(t, e, func() bool { return e.compilation.process == nil })
+	if text := b.text(0, b.length()); !strings.Contains(text, "Compilation killed") {
+		t.Errorf("expected the compilation to be killed, found %q\n", text)
+	}
+}
@@ -0,0 +1,64 @@
+package editor
+
+import "strings"
+
+// completion is the completion behaviour of a prompt. The candidates are
+// filtered with fuzzy matching as the input changes.
+type completion struct {
+	// source returns the candidates for input and the part of input they
+	// are matched against
+	source func(input string) ([]string, string)
+	// apply returns the input after choosing candidate, nil replaces the input
+	apply func(input string, candidate string) string
+	// complete does the unambiguous prefix completion of TAB, may be nil
+	complete func(input string) (string, []string)
+	// substitute rewrites the input after each insertion, may be nil
+	substitute func(input string) string
+	// descend tells if choosing a candidate that gave input keeps the
+	// prompt open instead of confirming it, may be nil
+	descend func(input string) bool
+	// selectFirst selects the first candidate, so that confirming chooses it
+	// instead of the input; otherwise one is selected only with C-n and C-p
+	selectFirst bool
+}
+
+func (c *completion) choose(input string, candidate string) string {
+	if c.apply == nil {
+		return candidate
+	}
+	return c.apply(input, candidate)
+}
+
+// listCompletion completes the whole input with the items returned by items.
+func listCompletion(items func() []string) *completion {
+	return &completion{
+		source: func(input string) ([]string, string) {
+			return items(), input
+		},
+		selectFirst: true,
+	}
+}
+
+var fileNameCompletion = &completion{
+	source: func(input string) ([]string, string) {
+		input = substituteFileName(input)
+		slash := strings.LastIndex(input, "/") + 1
+		return fileNameCandidates(input[:slash]), input[slash:]
+	},
+	apply: func(input string, candidate string) string {
+		input = substituteFileName(input)
+		return input[:strings.LastIndex(input, "/")+1] + candidate
+	},
+	complete:   completeFileName,
+	substitute: substituteFileName,
+	descend: func(input string) bool {
+		return strings.HasSuffix(input, "/")
+	},
+}
+
+// CompletingRead prompts for one of the strings returned by candidates,
+// filtering them as the user types. C-n and C-p select a candidate and RET
+// confirms it; with no candidate selected onConfirm gets the input as typed.
+func (e *Editor) CompletingRead(prompt string, candidates func() []string, history string, onConfirm func(input string)) {
+	e.readMinibuffer(prompt, "", listCompletion(candidates), history, onConfirm)
+}
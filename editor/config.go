@@ -0,0 +1,111 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config is the user configuration file. Every non empty line that does not
+// start with '#' is a setting of the form "<key> <args...>", e.g.
+//
+//	macro kill-two-lines C-k C-k
+//
+// Lines are kept as they were read so that saving does not lose comments or
+// settings this version does not understand.
+type Config struct {
+	path   string
+	lines  []string
+	Macros map[string]string // macro name -> key description
+	// RestoreSession restores the session of the working directory at
+	// startup, set with "restore-session yes"
+	RestoreSession bool
+	// Formatters holds the command formatting the buffers of a mode on save,
+	// set with e.g. "formatter go goimports"
+	Formatters map[string]string
+}
+
+// ConfigDir returns the directory holding goedit's persistent files.
+func ConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "goedit")
+}
+
+// LoadConfig reads the config file at path. A missing file is not an error
+// and results in an empty config that will be created at first save.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{
+		path:       path,
+		Macros:     make(map[string]string),
+		Formatters: make(map[string]string),
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return c, err
+	}
+	c.lines = strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	for _, line := range c.lines {
+		key, args := splitConfigLine(line)
+		switch key {
+		case "macro":
+			name, keys, found := strings.Cut(args, " ")
+			if found {
+				c.Macros[name] = strings.TrimSpace(keys)
+			}
+		case "restore-session":
+			c.RestoreSession = args == "yes"
+		case "formatter":
+			mode, command, found := strings.Cut(args, " ")
+			if found {
+				c.Formatters[mode] = strings.TrimSpace(command)
+			}
+		}
+	}
+	return c, nil
+}
+
+// SetMacro stores a named macro in the config file, replacing any previous
+// macro with the same name.
+func (c *Config) SetMacro(name string, keys string) error {
+	c.Macros[name] = keys
+	return c.set("macro", name, "macro "+name+" "+keys)
+}
+
+// set replaces the line for key and name or appends it, then writes the file.
+func (c *Config) set(key string, name string, line string) error {
+	replaced := false
+	for i, l := range c.lines {
+		k, args := splitConfigLine(l)
+		if k == key && (args == name || strings.HasPrefix(args, name+" ")) {
+			c.lines[i] = line
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		c.lines = append(c.lines, line)
+	}
+	return c.save()
+}
+
+func (c *Config) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, []byte(strings.Join(c.lines, "\n")+"\n"), 0644)
+}
+
+func splitConfigLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return "", ""
+	}
+	key, args, _ := strings.Cut(line, " ")
+	return key, strings.TrimSpace(args)
+}
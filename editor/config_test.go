@@ -0,0 +1,49 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigMacros(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := "# comment\nunknown setting\nmacro two-lines C-k C-k\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Macros["two-lines"] != "C-k C-k" {
+		t.Errorf("expected macro two-lines, found %q\n", c.Macros["two-lines"])
+	}
+
+	if err := c.SetMacro("two-lines", "C-a C-k C-k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetMacro("down", "C-n"); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# comment\nunknown setting\nmacro two-lines C-a C-k C-k\nmacro down C-n\n"
+	if string(saved) != expected {
+		t.Errorf("expected file %q, found %q\n", expected, string(saved))
+	}
+}
+
+func TestLoadMissingConfig(t *testing.T) {
+	c, err := LoadConfig(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Errorf("unexpected error %v\n", err)
+	}
+	if len(c.Macros) != 0 {
+		t.Errorf("expected no macros, found %v\n", c.Macros)
+	}
+}
@@ -0,0 +1,27 @@
+package editor
+
+// YOrNP asks a question answered with a single y or n key.
+func (e *Editor) YOrNP(question string, onAnswer func(yes bool)) {
+	e.Minibuffer.Choose(question+" (y or n) ", "yn", func(choice byte) {
+		onAnswer(choice == 'y')
+	}, nil)
+}
+
+// YesOrNoP asks a question that needs yes or no typed in full, for the
+// actions that are hard to undo.
+func (e *Editor) YesOrNoP(question string, onAnswer func(yes bool)) {
+	e.yesOrNo(question+" (yes or no) ", question, onAnswer)
+}
+
+func (e *Editor) yesOrNo(prompt string, question string, onAnswer func(yes bool)) {
+	e.ReadMinibuffer(prompt, func(input string) {
+		switch input {
+		case "yes":
+			onAnswer(true)
+		case "no":
+			onAnswer(false)
+		default:
+			e.yesOrNo("Please answer yes or no.  "+question+" (yes or no) ", question, onAnswer)
+		}
+	})
+}
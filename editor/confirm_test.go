@@ -0,0 +1,61 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYOrNP(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	e := CreateEditor()
+	var answers []bool
+	e.YOrNP("Save?", func(yes bool) { answers = append(answers, yes) })
+	e.Minibuffer.InsertAtCol("x")
+	if !strings.HasPrefix(e.Minibuffer.GetLine(), "Please answer y or n.") {
+		t.Errorf("expected the valid answers, found %q\n", e.Minibuffer.GetLine())
+	}
+	e.Minibuffer.ConfirmAction()
+	e.Minibuffer.InsertAtCol("y")
+	if len(answers) != 1 || !answers[0] || e.Minibuffer.Focused {
+		t.Errorf("expected a single yes, found %v\n", answers)
+	}
+}
+
+func TestYesOrNoP(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	e := CreateEditor()
+	var answers []bool
+	e.YesOrNoP("Kill?", func(yes bool) { answers = append(answers, yes) })
+	e.Minibuffer.SetInput("y")
+	e.Minibuffer.ConfirmAction()
+	if len(answers) != 0 || !e.Minibuffer.Focused {
+		t.Fatalf("expected the question again, found %v\n", answers)
+	}
+	e.Minibuffer.SetInput("no")
+	e.Minibuffer.ConfirmAction()
+	if len(answers) != 1 || answers[0] {
+		t.Errorf("expected no, found %v\n", answers)
+	}
+}
+
+func TestKillModifiedBuffer(t *testing.T) {
+	b := newTestBuffer(t, "main.go", "package main\n")
+	e := b.parent
+	b.Path = "main.go"
+	e.OpenBuffers = append(e.OpenBuffers, b)
+	e.CurrentBuffer = 1
+	b.Insert("x", true)
+
+	e.KillCurrentBuffer()
+	e.Minibuffer.SetInput("no")
+	e.Minibuffer.ConfirmAction()
+	if len(e.OpenBuffers) != 2 {
+		t.Fatalf("expected the buffer to be kept\n")
+	}
+	e.KillCurrentBuffer()
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	if len(e.OpenBuffers) != 1 || e.Quitting() {
+		t.Errorf("expected the buffer to be killed\n")
+	}
+}
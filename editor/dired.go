@@ -0,0 +1,550 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// marks of dired entries, shown in the first column
+const (
+	DIRED_MARK   = '*'
+	DIRED_DELETE = 'D'
+)
+
+var DiredMode = &Mode{
+	Name: "dired",
+	Keys: map[string]string{
+		"RET": "dired-find-file",
+		"f":   "dired-find-file",
+		"^":   "dired-up-directory",
+		"m":   "dired-mark",
+		"d":   "dired-flag-file-deletion",
+		"u":   "dired-unmark",
+		"x":   "dired-do-flagged-delete",
+		"R":   "dired-do-rename",
+		"C":   "dired-do-copy",
+		"+":   "dired-create-directory",
+		"g":   "revert-buffer",
+		"n":   "dired-next-line",
+		"p":   "dired-previous-line",
+	},
+}
+
+// dired is the state of a directory listing buffer. Row 0 is the directory
+// name, the entries start on row 1.
+type dired struct {
+	dir     string
+	entries []fs.FileInfo
+	marks   map[string]byte
+}
+
+// Dired shows the listing of dir, reusing its buffer if it is already open.
+func (e *Editor) Dired(dir string) *Buffer {
+	dir = absPath(dir)
+	for _, b := range e.OpenBuffers {
+		if b.dired != nil && b.dired.dir == dir {
+			e.showBuffer(b)
+			return b
+		}
+	}
+	b := newSpecialBuffer(e, abbreviateFileName(dir)+"/")
+	if dir == "/" {
+		b.Name = "/"
+	}
+	b.Mode = DiredMode
+	b.Dir = dir
+	b.dired = &dired{dir: dir, marks: make(map[string]byte)}
+	if err := e.diredRefresh(b); err != nil {
+		e.Minibuffer.SetMessage("Error reading directory: " + err.Error())
+		return nil
+	}
+	e.showBuffer(b)
+	return b
+}
+
+// diredRefresh lists the directory again, keeping the marks of the entries
+// that still exist and the cursor on the same entry if possible.
+func (e *Editor) diredRefresh(b *Buffer) error {
+	d := b.dired
+	current := d.entryName(b.CurrentRow())
+
+	dirEntries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	d.entries = d.entries[:0]
+	if parent, err := os.Stat(filepath.Join(d.dir, "..")); err == nil && d.dir != "/" {
+		d.entries = append(d.entries, namedInfo{parent, ".."})
+	}
+	var files []fs.FileInfo
+	for _, entry := range dirEntries {
+		if info, err := entry.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	d.entries = append(d.entries, files...)
+
+	marks := make(map[string]byte)
+	for _, info := range d.entries {
+		if mark, found := d.marks[info.Name()]; found {
+			marks[info.Name()] = mark
+		}
+	}
+	d.marks = marks
+
+	var sb strings.Builder
+	sb.WriteString("  " + abbreviateFileName(d.dir) + ":\n")
+	for _, info := range d.entries {
+		sb.WriteString(d.line(info) + "\n")
+	}
+	b.rewrite(func() { b.setText(sb.String()) })
+	b.GotoLine(1)
+	for row := 1; row <= len(d.entries); row++ {
+		if d.entryName(row) == current {
+			b.GotoLine(row)
+		}
+	}
+	return nil
+}
+
+// namedInfo renames a FileInfo, for the .. entry.
+type namedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (n namedInfo) Name() string {
+	return n.name
+}
+
+// line formats an entry like ls -l: mark, permissions, size, time and name.
+func (d *dired) line(info fs.FileInfo) string {
+	mark := byte(' ')
+	if m, found := d.marks[info.Name()]; found {
+		mark = m
+	}
+	name := info.Name()
+	if info.IsDir() {
+		name += "/"
+	} else if info.Mode()&fs.ModeSymlink != 0 {
+		if target, err := os.Readlink(filepath.Join(d.dir, info.Name())); err == nil {
+			name += " -> " + target
+		}
+	}
+	return fmt.Sprintf("%c %s %10d %s %s", mark, info.Mode().String(), info.Size(),
+		info.ModTime().Format("2006-01-02 15:04"), name)
+}
+
+// entryName returns the name of the entry on row, empty for the header.
+func (d *dired) entryName(row int) string {
+	if row < 1 || row > len(d.entries) {
+		return ""
+	}
+	return d.entries[row-1].Name()
+}
+
+// currentDired returns the current buffer if it is a dired buffer.
+func (e *Editor) currentDired() (*Buffer, bool) {
+	b := e.GetCurrentBuffer()
+	if b == nil || b.dired == nil {
+		e.Minibuffer.SetMessage("Not a directory listing")
+		return nil, false
+	}
+	return b, true
+}
+
+// diredSelection returns the paths of the marked entries, or of the entry at
+// the cursor when none is marked.
+func (b *Buffer) diredSelection() []string {
+	d := b.dired
+	var paths []string
+	for _, info := range d.entries {
+		if d.marks[info.Name()] == DIRED_MARK {
+			paths = append(paths, filepath.Join(d.dir, info.Name()))
+		}
+	}
+	if len(paths) == 0 {
+		if name := d.entryName(b.CurrentRow()); name != "" && name != ".." {
+			paths = append(paths, filepath.Join(d.dir, name))
+		}
+	}
+	return paths
+}
+
+// diredSetMark marks the entry at the cursor, 0 removing its mark, and
+// moves to the next line.
+func (e *Editor) diredSetMark(mark byte, count int) {
+	b, ok := e.currentDired()
+	if !ok {
+		return
+	}
+	d := b.dired
+	for i := 0; i < count; i++ {
+		row := b.CurrentRow()
+		name := d.entryName(row)
+		if name != "" && name != ".." {
+			if mark == 0 {
+				delete(d.marks, name)
+			} else {
+				d.marks[name] = mark
+			}
+			b.replaceRow(row, d.line(d.entries[row-1]))
+		}
+		b.GotoLine(min(row+1, len(d.entries)))
+	}
+}
+
+// replaceRow replaces the text of row in a special buffer, without recording
+// undo or marking the buffer modified, and leaves the cursor at its start.
+func (b *Buffer) replaceRow(row int, text string) {
+	start := b.rowStart(row)
+	b.moveTo(b.lineEnd(start))
+	b.gapStart = start
+	readOnly, markActive := b.ReadOnlyMode, b.markActive
+	b.ReadOnlyMode, b.markActive = false, false
+	b.rewrite(func() { b.Insert(text, false) })
+	b.ReadOnlyMode, b.markActive = readOnly, markActive
+	b.moveTo(start)
+}
+
+// rewrite runs edit, which only redraws the listing, without marking the
+// buffer modified.
+func (b *Buffer) rewrite(edit func()) {
+	modified, changes := b.Modified, b.changes
+	edit()
+	b.Modified, b.changes = modified, changes
+}
+
+func (e *Editor) diredFindFile() {
+	b, ok := e.currentDired()
+	if !ok {
+		return
+	}
+	name := b.dired.entryName(b.CurrentRow())
+	if name == "" {
+		return
+	}
+	e.FindFile(filepath.Join(b.dired.dir, name))
+}
+
+func (e *Editor) diredUpDirectory() {
+	b, ok := e.currentDired()
+	if !ok || b.dired.dir == "/" {
+		return
+	}
+	child := filepath.Base(b.dired.dir)
+	parent := e.Dired(filepath.Dir(b.dired.dir))
+	if parent == nil {
+		return
+	}
+	for row := 1; row <= len(parent.dired.entries); row++ {
+		if parent.dired.entryName(row) == child {
+			parent.GotoLine(row)
+		}
+	}
+}
+
+// diredDelete deletes the entries flagged for deletion, directories with
+// their content, after confirmation.
+func (e *Editor) diredDelete() {
+	b, ok := e.currentDired()
+	if !ok {
+		return
+	}
+	var paths []string
+	for _, info := range b.dired.entries {
+		if b.dired.marks[info.Name()] == DIRED_DELETE {
+			paths = append(paths, filepath.Join(b.dired.dir, info.Name()))
+		}
+	}
+	if len(paths) == 0 {
+		e.Minibuffer.SetMessage("(No deletions requested)")
+		return
+	}
+	e.YesOrNoP("Delete "+describeFiles(paths)+"?", func(yes bool) {
+		if !yes {
+			return
+		}
+		deleted := 0
+		var visiting []*Buffer
+		for _, path := range paths {
+			if err := os.RemoveAll(path); err != nil {
+				e.Minibuffer.SetMessage("Error deleting " + path + ": " + err.Error())
+				break
+			}
+			deleted += 1
+			visiting = append(visiting, e.buffersUnder(path)...)
+		}
+		e.diredRefreshAll()
+		if deleted == len(paths) {
+			e.Minibuffer.SetMessage("Deleted " + describeFiles(paths))
+		}
+		e.diredKillBuffers(visiting)
+	})
+}
+
+// buffersUnder returns the buffers visiting path, or a file inside it.
+func (e *Editor) buffersUnder(path string) []*Buffer {
+	var found []*Buffer
+	for _, b := range e.OpenBuffers {
+		if b.Path == "" || b.dired != nil {
+			continue
+		}
+		if _, ok := pathUnder(b.Path, path); ok {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+// pathUnder returns the part of path below dir, "" for dir itself, and
+// whether path is dir or inside it.
+func pathUnder(path string, dir string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return "", false
+	}
+	if abs == dir {
+		return "", true
+	}
+	if rest, ok := strings.CutPrefix(abs, dir+string(filepath.Separator)); ok {
+		return rest, true
+	}
+	return "", false
+}
+
+// diredKillBuffers offers to kill, one by one, the buffers of deleted files.
+func (e *Editor) diredKillBuffers(buffers []*Buffer) {
+	if len(buffers) == 0 {
+		return
+	}
+	b := buffers[0]
+	e.YOrNP("Kill buffer of "+b.Name+", too?", func(yes bool) {
+		if yes && e.isOpen(b) {
+			e.closeBuffer(b)
+		}
+		e.diredKillBuffers(buffers[1:])
+	})
+}
+
+// describeFiles names a single file, or counts several.
+func describeFiles(paths []string) string {
+	if len(paths) == 1 {
+		return filepath.Base(paths[0])
+	}
+	return strconv.Itoa(len(paths)) + " files"
+}
+
+// diredTransfer renames or copies the selected entries. A single entry can be
+// given any name, several go to a directory. Replacing a file asks first.
+func (e *Editor) diredTransfer(verb string, transfer func(from string, to string) error) {
+	b, ok := e.currentDired()
+	if !ok {
+		return
+	}
+	paths := b.diredSelection()
+	if len(paths) == 0 {
+		return
+	}
+	e.ReadFileName(verb+" "+describeFiles(paths)+" to: ", func(target string) {
+		info, err := os.Stat(target)
+		isDir := err == nil && info.IsDir()
+		if len(paths) > 1 && !isDir {
+			e.Minibuffer.SetMessage("Target must be a directory: " + target)
+			return
+		}
+		var todo [][2]string
+		for _, path := range paths {
+			to := target
+			if isDir {
+				to = filepath.Join(target, filepath.Base(path))
+			}
+			todo = append(todo, [2]string{path, to})
+		}
+		e.diredTransferEach(verb, todo, transfer)
+	})
+}
+
+func (e *Editor) diredTransferEach(verb string, todo [][2]string, transfer func(from string, to string) error) {
+	if len(todo) == 0 {
+		e.diredRefreshAll()
+		return
+	}
+	from, to := todo[0][0], todo[0][1]
+	do := func() {
+		if err := transfer(from, to); err != nil {
+			e.Minibuffer.SetMessage("Error: " + err.Error())
+			e.diredRefreshAll()
+			return
+		}
+		if verb == "Rename" {
+			e.renameBuffers(from, to)
+		}
+		e.Minibuffer.SetMessage(verb + " " + from + " to " + to)
+		e.diredTransferEach(verb, todo[1:], transfer)
+	}
+	if _, err := os.Lstat(to); err == nil {
+		e.YOrNP("Overwrite "+to+"?", func(yes bool) {
+			if yes {
+				do()
+			} else {
+				e.diredTransferEach(verb, todo[1:], transfer)
+			}
+		})
+		return
+	}
+	do()
+}
+
+// renameBuffers makes the buffers visiting from, or a file inside it, visit
+// the same file under to.
+func (e *Editor) renameBuffers(from string, to string) {
+	for _, b := range e.buffersUnder(from) {
+		rest, _ := pathUnder(b.Path, from)
+		path := filepath.Join(to, rest)
+		locked := b.locked
+		b.unlock()
+		e.unwatchFile(b.Path)
+		if b.Name == b.Path {
+			b.Name = path
+		}
+		b.Path = path
+		if err := e.watchFile(path); err != nil {
+			e.Minibuffer.SetMessage("Error watching " + path + ": " + err.Error())
+		}
+		if locked {
+			b.lock()
+		}
+	}
+}
+
+// copyTree copies a file, or a directory with its content, keeping permissions.
+// A directory cannot be copied inside itself.
+func copyTree(from string, to string) error {
+	if _, inside := pathUnder(to, from); inside {
+		return fmt.Errorf("cannot copy %s into itself", from)
+	}
+	return filepath.WalkDir(from, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(to, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(dest, info.Mode().Perm())
+		}
+		return copyFile(path, dest, info.Mode().Perm())
+	})
+}
+
+func copyFile(from string, to string, perm fs.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func (e *Editor) diredCreateDirectory() {
+	if _, ok := e.currentDired(); !ok {
+		return
+	}
+	e.ReadFileName("Create directory: ", func(dir string) {
+		if _, err := os.Stat(dir); err == nil {
+			e.Minibuffer.SetMessage("Cannot create directory " + dir + ": file exists")
+			return
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			e.Minibuffer.SetMessage("Error creating directory: " + err.Error())
+			return
+		}
+		e.diredRefreshAll()
+	})
+}
+
+// diredRefreshAll lists again every open directory, as an operation may have
+// changed several of them.
+func (e *Editor) diredRefreshAll() {
+	for _, b := range e.OpenBuffers {
+		if b.dired == nil {
+			continue
+		}
+		if err := e.diredRefresh(b); err != nil && !errors.Is(err, os.ErrNotExist) {
+			e.Minibuffer.SetMessage("Error reading directory: " + err.Error())
+		}
+	}
+}
+
+func (e *Editor) registerDiredCommands() {
+	e.RegisterCommand("dired", func(e *Editor, count int) {
+		e.ReadFileName("Dired (directory): ", func(dir string) {
+			e.Dired(dir)
+		})
+	})
+	e.RegisterCommand("dired-find-file", func(e *Editor, count int) {
+		e.diredFindFile()
+	})
+	e.RegisterCommand("dired-up-directory", func(e *Editor, count int) {
+		e.diredUpDirectory()
+	})
+	e.RegisterCommand("dired-mark", func(e *Editor, count int) {
+		e.diredSetMark(DIRED_MARK, count)
+	})
+	e.RegisterCommand("dired-flag-file-deletion", func(e *Editor, count int) {
+		e.diredSetMark(DIRED_DELETE, count)
+	})
+	e.RegisterCommand("dired-unmark", func(e *Editor, count int) {
+		e.diredSetMark(0, count)
+	})
+	e.RegisterCommand("dired-do-flagged-delete", func(e *Editor, count int) {
+		e.diredDelete()
+	})
+	e.RegisterCommand("dired-do-rename", func(e *Editor, count int) {
+		e.diredTransfer("Rename", os.Rename)
+	})
+	e.RegisterCommand("dired-do-copy", func(e *Editor, count int) {
+		e.diredTransfer("Copy", copyTree)
+	})
+	e.RegisterCommand("dired-create-directory", func(e *Editor, count int) {
+		e.diredCreateDirectory()
+	})
+	e.RegisterCommand("dired-next-line", func(e *Editor, count int) {
+		if b, ok := e.currentDired(); ok {
+			b.GotoLine(min(max(b.CurrentRow()+count, 1), len(b.dired.entries)))
+		}
+	})
+	e.RegisterCommand("dired-previous-line", func(e *Editor, count int) {
+		if b, ok := e.currentDired(); ok {
+			b.GotoLine(min(max(b.CurrentRow()-count, 1), len(b.dired.entries)))
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDired(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "a\n")
+	writeFile(t, filepath.Join(dir, "b.txt"), "b\n")
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+
+	e := CreateEditor()
+	b := e.FindFile(dir)
+	if b == nil || b.dired == nil || e.GetCurrentBuffer() != b {
+		t.Fatalf("expected a directory listing\n")
+	}
+	lines := strings.Split(b.text(0, b.length()), "\n")
+	if len(lines) != 6 || !strings.HasSuffix(lines[1], " ../") || !strings.HasSuffix(lines[4], " sub/") {
+		t.Fatalf("unexpected listing %q\n", lines)
+	}
+	if !strings.HasPrefix(lines[2], "  -rw-") || !strings.Contains(lines[2], " 2 ") {
+		t.Errorf("expected the permissions and size, found %q\n", lines[2])
+	}
+
+	b.GotoLine(2)
+	e.ExecuteCommand("dired-flag-file-deletion", 1)
+	if line := b.text(b.rowStart(2), b.lineEnd(b.rowStart(2))); line[0] != 'D' || b.CurrentRow() != 3 {
+		t.Errorf("expected a.txt to be flagged and the cursor on the next line, found %q\n", line)
+	}
+	if b.Modified || b.changes != 0 {
+		t.Errorf("expected marking not to modify the listing\n")
+	}
+	e.ExecuteCommand("dired-do-flagged-delete", 1)
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected a.txt to be deleted\n")
+	}
+	if name := b.dired.entryName(b.CurrentRow()); name != "b.txt" {
+		t.Errorf("expected the cursor to stay on b.txt, found %s\n", name)
+	}
+
+	e.ExecuteCommand("dired-do-rename", 1)
+	e.Minibuffer.SetInput(filepath.Join(dir, "sub") + "/")
+	e.Minibuffer.ConfirmAction()
+	if _, err := os.Stat(filepath.Join(dir, "sub", "b.txt")); err != nil {
+		t.Errorf("expected b.txt to be moved: %v\n", err)
+	}
+
+	b.GotoLine(2)
+	e.ExecuteCommand("dired-find-file", 1)
+	sub := e.GetCurrentBuffer()
+	if sub.dired == nil || sub.dired.dir != filepath.Join(dir, "sub") || sub.Dir != sub.dired.dir {
+		t.Fatalf("expected to list sub, found %s\n", sub.Name)
+	}
+	sub.GotoLine(2)
+	e.ExecuteCommand("dired-do-copy", 1)
+	e.Minibuffer.SetInput(filepath.Join(dir, "c.txt"))
+	e.Minibuffer.ConfirmAction()
+	if data, err := os.ReadFile(filepath.Join(dir, "c.txt")); err != nil || string(data) != "b\n" {
+		t.Errorf("expected a copy of b.txt, found %q %v\n", data, err)
+	}
+	if b.dired.entryName(2) != "c.txt" {
+		t.Errorf("expected the parent listing to be refreshed\n")
+	}
+
+	e.ExecuteCommand("dired-up-directory", 1)
+	if e.GetCurrentBuffer() != b || b.dired.entryName(b.CurrentRow()) != "sub" {
+		t.Errorf("expected to return to the parent on sub\n")
+	}
+}
+
+func TestDiredCopyIntoItself(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	writeFile(t, filepath.Join(dir, "sub", "a.txt"), "a\n")
+	if err := copyTree(filepath.Join(dir, "sub"), filepath.Join(dir, "sub", "deeper")); err == nil {
+		t.Errorf("expected copying a directory into itself to fail\n")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "deeper")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be copied\n")
+	}
+	if err := copyTree(filepath.Join(dir, "sub"), filepath.Join(dir, "subway")); err != nil {
+		t.Errorf("expected a sibling with a common prefix to be a valid target: %v\n", err)
+	}
+}
+
+func TestDiredRenameAndDeleteVisitedFiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	writeFile(t, filepath.Join(dir, "sub", "a.txt"), "a\n")
+	writeFile(t, filepath.Join(dir, "b.txt"), "b\n")
+
+	e := CreateEditor()
+	a := e.FindFile(filepath.Join(dir, "sub", "a.txt"))
+	b := e.FindFile(filepath.Join(dir, "b.txt"))
+	d := e.FindFile(dir)
+	d.GotoLine(3)
+	if name := d.dired.entryName(3); name != "sub" {
+		t.Fatalf("expected sub on the third line, found %s\n", name)
+	}
+	e.ExecuteCommand("dired-do-rename", 1)
+	e.Minibuffer.SetInput(filepath.Join(dir, "moved"))
+	e.Minibuffer.ConfirmAction()
+	if want := filepath.Join(dir, "moved", "a.txt"); a.Path != want || a.Name != want {
+		t.Errorf("expected the buffer to visit %s, found %s\n", want, a.Path)
+	}
+
+	d.GotoLine(2)
+	e.ExecuteCommand("dired-flag-file-deletion", 1)
+	e.ExecuteCommand("dired-do-flagged-delete", 1)
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	if !e.isOpen(b) {
+		t.Fatalf("expected the buffer to stay open until confirmed\n")
+	}
+	e.Minibuffer.InsertAtCol("y")
+	if e.isOpen(b) {
+		t.Errorf("expected the buffer of the deleted file to be killed\n")
+	}
+	if !e.isOpen(a) {
+		t.Errorf("expected the other buffers to stay open\n")
+	}
+}
@@ -2,6 +2,7 @@ package editor
 
 import (
 	"os"
+	"path/filepath"
 )
 
 const (
@@ -11,20 +12,53 @@ const (
 )
 
 type Editor struct {
-	OpenBuffers     []*Buffer
-	CurrentBuffer   int
-	Minibuffer      *Minibuffer
-	MinibufferReady <-chan bool
+	OpenBuffers   []*Buffer
+	CurrentBuffer int
+	Minibuffer    *Minibuffer
+	Config        *Config
+	History       *History
+	Places        *Places
+	Messages      *Buffer
+	commands      map[string]Command
+	events        chan func()
+	quitting      bool
+	watcher       *fileWatcher
+	compilation   *compilation
+	grep          *grepSearch
+	shell         *shellCommand
+	PrefixArg     bool    // a prefix argument was given to the running command
+	errorBuffer   *Buffer // buffer whose locations next-error visits
 }
 
 func CreateEditor() *Editor {
-	ready := make(chan bool, 1)
 	editor := &Editor{
-		CurrentBuffer:   0,
-		Minibuffer:      NewMinibuffer(ready),
-		MinibufferReady: ready,
+		CurrentBuffer: 0,
+		Minibuffer:    NewMinibuffer(),
+		commands:      make(map[string]Command),
+		events:        make(chan func(), EVENTS_QUEUE),
 	}
 	editor.OpenBuffers = []*Buffer{NewEmptyBuffer(editor)}
+	editor.Messages = newSpecialBuffer(editor, MESSAGES_BUFFER)
+	editor.Minibuffer.logMessage = editor.logMessage
+	editor.registerCommands()
+
+	config, err := LoadConfig(filepath.Join(ConfigDir(), "config"))
+	if err != nil {
+		editor.Minibuffer.SetMessage("Error reading config: " + err.Error())
+	}
+	editor.Config = config
+
+	history, err := LoadHistory(filepath.Join(ConfigDir(), "history"))
+	if err != nil {
+		editor.Minibuffer.SetMessage("Error reading history: " + err.Error())
+	}
+	editor.History = history
+
+	places, err := LoadPlaces(filepath.Join(ConfigDir(), "places"))
+	if err != nil {
+		editor.Minibuffer.SetMessage("Error reading places: " + err.Error())
+	}
+	editor.Places = places
 	return editor
 }
 
@@ -35,6 +69,68 @@ func (e *Editor) GetCurrentBuffer() *Buffer {
 	return nil
 }
 
+// Quit asks the UI to exit once the current event is handled.
+func (e *Editor) Quit() {
+	e.quitting = true
+	for _, b := range e.OpenBuffers {
+		b.unlock()
+	}
+	e.closeWatcher()
+	if e.compilation != nil && e.compilation.process != nil {
+		killProcess(e.compilation.process)
+	}
+	if e.grep != nil && e.grep.process != nil {
+		killProcess(e.grep.process)
+	}
+	if e.shell != nil {
+		killProcess(e.shell.process)
+	}
+}
+
+func (e *Editor) Quitting() bool {
+	return e.quitting
+}
+
+// KillCurrentBuffer closes the current buffer, asking first when it has
+// unsaved changes. Closing the last buffer exits the editor.
+func (e *Editor) KillCurrentBuffer() {
+	b := e.GetCurrentBuffer()
+	if b == nil {
+		return
+	}
+	if b.Modified && b.Path != "" {
+		e.YesOrNoP("Buffer "+b.Name+" modified; kill anyway?", func(yes bool) {
+			if yes {
+				e.closeBuffer(b)
+			}
+		})
+		return
+	}
+	e.closeBuffer(b)
+}
+
+// closeBuffer closes b, releasing the lock and the watch of its file and
+// remembering the cursor position in it. Closing the last buffer exits the
+// editor.
+func (e *Editor) closeBuffer(b *Buffer) {
+	b.unlock()
+	e.unwatchFile(b.Path)
+	e.Places.Remember(b)
+	if err := e.Places.Save(); err != nil {
+		e.Minibuffer.SetMessage("Error saving places: " + err.Error())
+	}
+	for i, open := range e.OpenBuffers {
+		if open == b {
+			e.CurrentBuffer = i
+			e.CloseCurrentBuffer()
+			break
+		}
+	}
+	if len(e.OpenBuffers) == 0 {
+		e.Quit()
+	}
+}
+
 func (e *Editor) CloseCurrentBuffer() {
 	if len(e.OpenBuffers) > 0 {
 		if e.CurrentBuffer > 0 {
@@ -49,37 +145,98 @@ func (e *Editor) CloseCurrentBuffer() {
 	}
 }
 
-func (e *Editor) OpenBuffer() {
-	if e.Minibuffer.Focused {
-		return
+// SwitchBuffer prompts for the name of an open buffer and makes it current.
+func (e *Editor) SwitchBuffer() {
+	e.CompletingRead("Switch to buffer: ", e.bufferNames, "", func(name string) {
+		for i, b := range e.OpenBuffers {
+			if b.Name == name {
+				e.CurrentBuffer = i
+				return
+			}
+		}
+		e.Minibuffer.SetMessage("No buffer named " + name)
+	})
+}
+
+// bufferNames returns the names of the open buffers, the current one last.
+func (e *Editor) bufferNames() []string {
+	names := make([]string, 0, len(e.OpenBuffers))
+	for i, b := range e.OpenBuffers {
+		if i != e.CurrentBuffer {
+			names = append(names, b.Name)
+		}
 	}
+	if b := e.GetCurrentBuffer(); b != nil {
+		names = append(names, b.Name)
+	}
+	return names
+}
 
-	e.Minibuffer.Focused = true
-	e.Minibuffer.SetMessage("Find file: ")
-	ready := <-e.MinibufferReady
-	defer func() {
-		e.Minibuffer.Focused = false
-	}()
+// ReadMinibuffer prompts for an input and calls onConfirm with it once the
+// user confirms it.
+func (e *Editor) ReadMinibuffer(prompt string, onConfirm func(input string)) {
+	e.readMinibuffer(prompt, "", nil, "", onConfirm)
+}
 
-	if !ready {
-		e.Minibuffer.SetMessage("Quit")
-		return
-	}
+// ReadFileName prompts for a file name, starting in the directory of the
+// current buffer with TAB completion. The path given to onConfirm has ~
+// expanded.
+func (e *Editor) ReadFileName(prompt string, onConfirm func(path string)) {
+	e.readMinibuffer(prompt, e.defaultDirectory(), fileNameCompletion, HISTORY_FILES, func(input string) {
+		onConfirm(expandFileName(input))
+	})
+}
 
-	path := e.Minibuffer.ConsumeInput()
+// readMinibuffer prompts for an input. c is the completion used by TAB, if
+// any, and history the kind of history navigated with M-p and M-n, which
+// records the input when it is not empty.
+func (e *Editor) readMinibuffer(prompt string, initial string, c *completion, history string, onConfirm func(input string)) {
+	e.Minibuffer.readPrompt(prompt, initial, c, e.History.Items(history), func(input string) {
+		if err := e.History.Add(history, input); err != nil {
+			e.Minibuffer.SetMessage("Error saving history: " + err.Error())
+		}
+		onConfirm(input)
+	}, nil)
+}
 
+func (e *Editor) OpenBuffer() {
+	e.ReadFileName("Find file: ", func(path string) {
+		e.FindFile(path)
+	})
+}
+
+// FindFile makes the buffer visiting path current, reading the file if no
+// buffer visits it yet, or lists path if it is a directory. It returns nil
+// when the file cannot be read. Visited files go to the recent files.
+func (e *Editor) FindFile(path string) *Buffer {
+	b := e.visitFile(path)
+	if b != nil && b.dired == nil {
+		e.addRecentFile(b.Path)
+	}
+	return b
+}
+
+// visitFile is FindFile without recording the file, for jumps to a location
+// like next-error.
+func (e *Editor) visitFile(path string) *Buffer {
 	if path == "" {
 		e.Minibuffer.SetMessage("Empty path")
-		return
+		return nil
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return e.Dired(path)
+	}
+	if i := e.findBuffer(path); i >= 0 {
+		e.CurrentBuffer = i
+		return e.OpenBuffers[i]
 	}
 
 	fileInfo, err := os.Stat(path)
 	if err != nil {
 		// open a fake file. It will be created at first save
-		e.OpenBuffers = append(e.OpenBuffers, NewBuffer(e, path, []byte(""), false))
-		e.CurrentBuffer = len(e.OpenBuffers) - 1
-		e.Minibuffer.SetMessage("Done")
-		return
+		b := NewBuffer(e, path, []byte(""), false)
+		e.addBuffer(b)
+		return b
 	}
 	fileSize := fileInfo.Size()
 	readOnlyMode := false
@@ -88,13 +245,57 @@ func (e *Editor) OpenBuffer() {
 		readOnlyMode = true
 	}
 
-	content, err := os.ReadFile(path)
+	raw, err := os.ReadFile(path)
 	if err != nil {
 		e.Minibuffer.SetMessage("Error reading file")
-		return
+		return nil
 	}
 
-	e.OpenBuffers = append(e.OpenBuffers, NewBuffer(e, path, content, readOnlyMode))
-	e.CurrentBuffer = len(e.OpenBuffers) - 1
+	content, charset := decodeCharset(raw)
+	content, lineEnding := decodeLineEndings(content)
+	b := NewBuffer(e, path, content, readOnlyMode)
+	b.Charset = charset
+	b.LineEnding = lineEnding
+	b.recordDiskState(fileInfo, raw)
+	if pos, found := e.Places.Get(path); found {
+		b.moveTo(min(pos, b.length()))
+	}
+	e.addBuffer(b)
+	return b
+}
+
+// findBuffer returns the index of the buffer visiting path, or -1.
+func (e *Editor) findBuffer(path string) int {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return -1
+	}
+	for i, b := range e.OpenBuffers {
+		if b.Path == "" {
+			continue
+		}
+		if p, err := filepath.Abs(b.Path); err == nil && p == abs {
+			return i
+		}
+	}
+	return -1
+}
+
+// addBuffer makes a newly opened file buffer the current one.
+func (e *Editor) addBuffer(b *Buffer) {
 	e.Minibuffer.SetMessage("Done")
+	if err := b.ApplyEditorConfig(); err != nil {
+		e.Minibuffer.SetMessage("Error reading .editorconfig: " + err.Error())
+	}
+	e.OpenBuffers = append(e.OpenBuffers, b)
+	e.CurrentBuffer = len(e.OpenBuffers) - 1
+	if err := e.watchFile(b.Path); err != nil {
+		e.Minibuffer.SetMessage("Error watching " + b.Path + ": " + err.Error())
+	}
+	if hasNewerAutoSave(b.Path) {
+		e.Minibuffer.SetMessage(b.Name + " has auto save data; consider M-x recover-file")
+	}
+	// last, as the question would be hidden by a later message
+	b.locking = true
+	e.checkLock(b)
 }
@@ -0,0 +1,275 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const EDITORCONFIG = ".editorconfig"
+
+type editorConfigSection struct {
+	pattern *regexp.Regexp
+	props   map[string]string
+}
+
+// editorConfigProperties returns the .editorconfig properties that apply to
+// path. Files are read walking up from the directory of path until one with
+// root = true. Closer files and later sections take precedence.
+func editorConfigProperties(path string) (map[string]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	abs = filepath.ToSlash(abs)
+
+	files := make([][]editorConfigSection, 0)
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		sections, root, err := readEditorConfig(dir)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sections)
+		if root || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	props := make(map[string]string)
+	for i := len(files) - 1; i >= 0; i-- {
+		for _, section := range files[i] {
+			if section.pattern.MatchString(abs) {
+				for k, v := range section.props {
+					props[k] = v
+				}
+			}
+		}
+	}
+	return props, nil
+}
+
+// readEditorConfig parses the .editorconfig in dir, if there is one.
+func readEditorConfig(dir string) ([]editorConfigSection, bool, error) {
+	f, err := os.Open(filepath.Join(dir, EDITORCONFIG))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	sections := make([]editorConfigSection, 0)
+	root := false
+	var current *editorConfigSection
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && strings.HasSuffix(line, "]") {
+			pattern, err := editorConfigPattern(dir, line[1:len(line)-1])
+			if err != nil {
+				// an invalid section is ignored, like other editors do
+				current = nil
+				continue
+			}
+			sections = append(sections, editorConfigSection{pattern, make(map[string]string)})
+			current = &sections[len(sections)-1]
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		if current == nil {
+			if key == "root" && value == "true" {
+				root = true
+			}
+		} else {
+			current.props[key] = value
+		}
+	}
+	return sections, root, scanner.Err()
+}
+
+// editorConfigPattern converts a section glob of the .editorconfig in dir to
+// a regexp matching absolute paths. Globs without a slash match file names
+// in any subdirectory.
+func editorConfigPattern(dir string, glob string) (*regexp.Regexp, error) {
+	prefix := regexp.QuoteMeta(strings.TrimSuffix(filepath.ToSlash(dir), "/")) + "/"
+	if strings.Contains(glob, "/") {
+		glob = strings.TrimPrefix(glob, "/")
+	} else {
+		prefix += "(?:.*/)?"
+	}
+	return regexp.Compile("^" + prefix + translateGlob(glob) + "$")
+}
+
+var numRange = regexp.MustCompile(`^(-?\d+)\.\.(-?\d+)$`)
+
+// translateGlob converts an editorconfig glob to a regexp, supporting
+// *, **, ?, [chars], [!chars], {a,b} and {num1..num2}.
+func translateGlob(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			chars := glob[i+1 : i+1+end]
+			if strings.HasPrefix(chars, "!") {
+				sb.WriteString("[^" + strings.ReplaceAll(chars[1:], `\`, `\\`) + "]")
+			} else {
+				sb.WriteString("[" + strings.ReplaceAll(chars, `\`, `\\`) + "]")
+			}
+			i += end + 1
+		case '{':
+			end := matchingBrace(glob, i)
+			if end < 0 {
+				sb.WriteString(`\{`)
+				continue
+			}
+			sb.WriteString(translateBraces(glob[i+1 : end]))
+			i = end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+func matchingBrace(glob string, start int) int {
+	depth := 0
+	for i := start; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func translateBraces(inner string) string {
+	if m := numRange.FindStringSubmatch(inner); m != nil {
+		from, _ := strconv.Atoi(m[1])
+		to, _ := strconv.Atoi(m[2])
+		if from > to {
+			from, to = to, from
+		}
+		if to-from > 1000 {
+			return `-?\d+`
+		}
+		alternatives := make([]string, 0, to-from+1)
+		for n := from; n <= to; n++ {
+			alternatives = append(alternatives, strconv.Itoa(n))
+		}
+		return "(?:" + strings.Join(alternatives, "|") + ")"
+	}
+
+	// split on the commas that are not inside nested braces
+	parts := make([]string, 0)
+	depth, last := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, inner[last:i])
+				last = i + 1
+			}
+		}
+	}
+	parts = append(parts, inner[last:])
+	if len(parts) == 1 {
+		return regexp.QuoteMeta("{") + translateGlob(inner) + regexp.QuoteMeta("}")
+	}
+	for i, part := range parts {
+		parts[i] = translateGlob(part)
+	}
+	return "(?:" + strings.Join(parts, "|") + ")"
+}
+
+// ApplyEditorConfig sets the buffer options from the .editorconfig files
+// that apply to its file.
+func (b *Buffer) ApplyEditorConfig() error {
+	props, err := editorConfigProperties(b.Path)
+	if err != nil {
+		return err
+	}
+
+	switch props["indent_style"] {
+	case "tab":
+		b.IndentWithSpaces = false
+	case "space":
+		b.IndentWithSpaces = true
+	}
+	if width, err := strconv.Atoi(props["tab_width"]); err == nil && width > 0 {
+		b.TabWidth = width
+	}
+	if props["indent_size"] == "tab" {
+		b.IndentWidth = b.TabWidth
+	} else if size, err := strconv.Atoi(props["indent_size"]); err == nil && size > 0 {
+		b.IndentWidth = size
+		if _, found := props["tab_width"]; !found {
+			b.TabWidth = size
+		}
+	}
+	switch props["end_of_line"] {
+	case "lf", "crlf", "cr":
+		b.LineEnding = props["end_of_line"]
+	}
+	switch props["charset"] {
+	case "utf-8", "utf-8-bom", "latin1", "utf-16le", "utf-16be":
+		b.Charset = props["charset"]
+	}
+	switch props["trim_trailing_whitespace"] {
+	case "true":
+		b.TrimTrailingWhitespace = true
+	case "false":
+		b.TrimTrailingWhitespace = false
+	}
+	switch props["insert_final_newline"] {
+	case "true":
+		b.InsertFinalNewline = true
+	case "false":
+		b.InsertFinalNewline = false
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorConfigPattern(t *testing.T) {
+	testData := []struct {
+		glob     string
+		path     string
+		expected bool
+	}{
+		{"*", "/p/a.go", true},
+		{"*.go", "/p/sub/dir/a.go", true},
+		{"*.go", "/p/a.go.txt", false},
+		{"*.{js,ts}", "/p/x/b.ts", true},
+		{"*.{js,ts}", "/p/x/b.py", false},
+		{"lib/*.py", "/p/lib/a.py", true},
+		{"lib/*.py", "/p/lib/x/a.py", false},
+		{"lib/**.py", "/p/lib/x/a.py", true},
+		{"/Makefile", "/p/Makefile", true},
+		{"/Makefile", "/p/sub/Makefile", false},
+		{"file{1..3}.txt", "/p/file2.txt", true},
+		{"file{1..3}.txt", "/p/file4.txt", false},
+		{"[ab].c", "/p/b.c", true},
+		{"[!ab].c", "/p/b.c", false},
+		{"?.md", "/p/x.md", true},
+		{"{a,{b,c}}.md", "/p/c.md", true},
+	}
+
+	for _, data := range testData {
+		re, err := editorConfigPattern("/p", data.glob)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v\n", data.glob, err)
+			continue
+		}
+		if re.MatchString(data.path) != data.expected {
+			t.Errorf("%q on %q: expected %v\n", data.glob, data.path, data.expected)
+		}
+	}
+}
+
+func TestApplyEditorConfig(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, EDITORCONFIG), `root = true
+
+[*]
+indent_style = space
+indent_size = 4
+end_of_line = crlf
+insert_final_newline = true
+
+[*.go]
+indent_style = tab
+tab_width = 8
+`)
+	writeFile(t, filepath.Join(sub, EDITORCONFIG), `
+[*.go]
+Trim_Trailing_Whitespace = TRUE
+indent_size = tab
+`)
+
+	b := newTestBuffer(t, filepath.Join(sub, "a.go"), "")
+	if err := b.ApplyEditorConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if b.IndentWithSpaces || b.TabWidth != 8 || b.IndentWidth != 8 {
+		t.Errorf("unexpected indentation %v %d %d\n", b.IndentWithSpaces, b.TabWidth, b.IndentWidth)
+	}
+	if b.LineEnding != "crlf" || !b.InsertFinalNewline || !b.TrimTrailingWhitespace {
+		t.Errorf("unexpected save options %s %v %v\n", b.LineEnding, b.InsertFinalNewline, b.TrimTrailingWhitespace)
+	}
+
+	b = newTestBuffer(t, filepath.Join(root, "a.txt"), "")
+	if err := b.ApplyEditorConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if !b.IndentWithSpaces || b.TabWidth != 4 || b.IndentWidth != 4 || b.TrimTrailingWhitespace {
+		t.Errorf("unexpected options for txt file %v %d %d\n", b.IndentWithSpaces, b.TabWidth, b.IndentWidth)
+	}
+}
+
+func TestSaveOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	b := newTestBuffer(t, path, "a  \nb\t\nc")
+	b.TrimTrailingWhitespace = true
+	b.InsertFinalNewline = true
+	b.LineEnding = "crlf"
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "a\r\nb\r\nc\r\n" {
+		t.Errorf("unexpected saved content %q\n", content)
+	}
+	if res := b.text(0, b.length()); res != "a\nb\nc\n" {
+		t.Errorf("unexpected buffer content %q\n", res)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,27 @@
+package editor
+
+import "time"
+
+// EVENTS_QUEUE is the number of posted functions that can wait for the UI
+// goroutine before Post blocks
+const EVENTS_QUEUE = 256
+
+// Post queues fn to run on the UI goroutine. It is how background work, like
+// reading a file or running a process, hands its results to the editor: the
+// editor state must only be touched from the UI goroutine.
+func (e *Editor) Post(fn func()) {
+	e.events <- fn
+}
+
+// Events returns the functions queued with Post, for the UI main loop.
+func (e *Editor) Events() <-chan func() {
+	return e.events
+}
+
+// AfterFunc runs fn on the UI goroutine once d has elapsed. The returned
+// timer can stop it before it is posted.
+func (e *Editor) AfterFunc(d time.Duration, fn func()) *time.Timer {
+	return time.AfterFunc(d, func() {
+		e.Post(fn)
+	})
+}
@@ -0,0 +1,25 @@
+package editor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPostedEvents(t *testing.T) {
+	e := &Editor{events: make(chan func(), EVENTS_QUEUE)}
+	var order []string
+	e.AfterFunc(50*time.Millisecond, func() { order = append(order, "timer") })
+	go e.Post(func() { order = append(order, "posted") })
+
+	for len(order) < 2 {
+		select {
+		case fn := <-e.Events():
+			fn()
+		case <-time.After(time.Second):
+			t.Fatalf("expected two events, found %v\n", order)
+		}
+	}
+	if order[0] != "posted" || order[1] != "timer" {
+		t.Errorf("expected posted then timer, found %v\n", order)
+	}
+}
@@ -0,0 +1,232 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Save writes the buffer to its file, applying the buffer's save options.
+func (b *Buffer) Save() error {
+	if b.Path == "" {
+		return fmt.Errorf("Buffer %s is not visiting a file", b.Name)
+	}
+	if b.ReadOnlyMode {
+		return fmt.Errorf("Buffer %s is read only", b.Name)
+	}
+	if b.markActive {
+		b.ToggleMark()
+	}
+
+	b.undo.BeginGroup()
+	if err := b.format(); err != nil {
+		b.undo.EndGroup()
+		return err
+	}
+	if b.TrimTrailingWhitespace {
+		b.deleteTrailingWhitespace()
+	}
+	if b.InsertFinalNewline && b.length() > 0 && b.byteAt(b.length()-1) != '\n' {
+		cursor := b.gapStart
+		b.moveTo(b.length())
+		b.Insert("\n", true)
+		b.moveTo(cursor)
+	}
+	b.undo.EndGroup()
+
+	content, err := encodeContent([]byte(b.text(0, b.length())), b.LineEnding, b.Charset)
+	if err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(b.Path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.WriteFile(b.Path, content, perm); err != nil {
+		return err
+	}
+	if info, err := os.Stat(b.Path); err == nil {
+		b.recordDiskState(info, content)
+	}
+	b.Modified = false
+	b.unlock()
+	b.parent.Minibuffer.SetMessage("Wrote " + b.Path)
+	if err := b.removeAutoSave(); err != nil {
+		b.parent.Minibuffer.SetMessage("Error deleting auto-save file: " + err.Error())
+	}
+	return nil
+}
+
+// deleteTrailingWhitespace removes blanks at the end of every line.
+func (b *Buffer) deleteTrailingWhitespace() {
+	for start := 0; start <= b.length(); {
+		end := b.lineEnd(start)
+		trimmed := end
+		for trimmed > start && isBlank(b.byteAt(trimmed-1)) {
+			trimmed -= 1
+		}
+		if trimmed < end {
+			b.replaceLine(start, b.text(start, trimmed))
+		}
+		start = b.lineEnd(start) + 1
+	}
+}
+
+// decodeCharset detects the encoding of content from its byte order mark,
+// falling back to latin1 when it is not valid utf-8, and returns the content
+// converted to utf-8.
+func decodeCharset(content []byte) ([]byte, string) {
+	switch {
+	case bytes.HasPrefix(content, []byte("\xef\xbb\xbf")):
+		return content[3:], "utf-8-bom"
+	case bytes.HasPrefix(content, []byte("\xff\xfe")):
+		return decodeUTF16(content[2:], false), "utf-16le"
+	case bytes.HasPrefix(content, []byte("\xfe\xff")):
+		return decodeUTF16(content[2:], true), "utf-16be"
+	case utf8.Valid(content):
+		return content, "utf-8"
+	}
+	return decodeLatin1(content), "latin1"
+}
+
+// decodeAs converts content, without a byte order mark, from charset to utf-8.
+func decodeAs(content []byte, charset string) []byte {
+	switch charset {
+	case "utf-16le":
+		return decodeUTF16(content, false)
+	case "utf-16be":
+		return decodeUTF16(content, true)
+	case "latin1":
+		return decodeLatin1(content)
+	}
+	return content
+}
+
+func decodeLatin1(content []byte) []byte {
+	res := make([]byte, 0, len(content)+len(content)/4)
+	for _, c := range content {
+		res = utf8.AppendRune(res, rune(c))
+	}
+	return res
+}
+
+func decodeUTF16(content []byte, bigEndian bool) []byte {
+	units := make([]uint16, 0, len(content)/2)
+	for i := 0; i+1 < len(content); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(content[i])<<8|uint16(content[i+1]))
+		} else {
+			units = append(units, uint16(content[i+1])<<8|uint16(content[i]))
+		}
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+var codingAliases = map[string]string{
+	"utf-8":                "utf-8",
+	"utf8":                 "utf-8",
+	"utf-8-bom":            "utf-8-bom",
+	"utf-8-with-signature": "utf-8-bom",
+	"latin1":               "latin1",
+	"latin-1":              "latin1",
+	"iso-latin-1":          "latin1",
+	"iso-8859-1":           "latin1",
+	"utf-16le":             "utf-16le",
+	"utf-16be":             "utf-16be",
+}
+
+var codingLineEndings = map[string]string{
+	"-unix": "lf",
+	"-dos":  "crlf",
+	"-mac":  "cr",
+}
+
+// parseCodingSystem parses an emacs like coding system name, e.g. latin-1 or
+// utf-8-dos. The line ending is empty when the name has no eol suffix.
+func parseCodingSystem(name string) (string, string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	lineEnding := ""
+	for suffix, le := range codingLineEndings {
+		if strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			lineEnding = le
+			break
+		}
+	}
+	charset, ok := codingAliases[name]
+	return charset, lineEnding, ok
+}
+
+// decodeLineEndings detects the most common line ending of content and
+// returns the content with every line ending converted to \n.
+func decodeLineEndings(content []byte) ([]byte, string) {
+	crlf, cr, lf := 0, 0, 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\r' {
+			if i+1 < len(content) && content[i+1] == '\n' {
+				crlf++
+				i++
+			} else {
+				cr++
+			}
+		} else if content[i] == '\n' {
+			lf++
+		}
+	}
+	if crlf == 0 && cr == 0 {
+		return content, "lf"
+	}
+
+	lineEnding := "lf"
+	if crlf > lf && crlf >= cr {
+		lineEnding = "crlf"
+	} else if cr > lf && cr > crlf {
+		lineEnding = "cr"
+	}
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	content = bytes.ReplaceAll(content, []byte("\r"), []byte("\n"))
+	return content, lineEnding
+}
+
+// encodeContent converts the buffer text, always utf-8 with \n line endings,
+// to the line ending and charset of the file.
+func encodeContent(text []byte, lineEnding string, charset string) ([]byte, error) {
+	switch lineEnding {
+	case "crlf":
+		text = bytes.ReplaceAll(text, []byte("\n"), []byte("\r\n"))
+	case "cr":
+		text = bytes.ReplaceAll(text, []byte("\n"), []byte("\r"))
+	}
+
+	switch charset {
+	case "", "utf-8":
+		return text, nil
+	case "utf-8-bom":
+		return append([]byte("\xef\xbb\xbf"), text...), nil
+	case "latin1":
+		res := make([]byte, 0, len(text))
+		for _, r := range string(text) {
+			if r > 0xff || r == utf8.RuneError {
+				return nil, fmt.Errorf("Cannot encode %q as latin1", r)
+			}
+			res = append(res, byte(r))
+		}
+		return res, nil
+	case "utf-16le", "utf-16be":
+		units := utf16.Encode([]rune("\ufeff" + string(text)))
+		res := make([]byte, 0, 2*len(units))
+		for _, u := range units {
+			if charset == "utf-16le" {
+				res = append(res, byte(u), byte(u>>8))
+			} else {
+				res = append(res, byte(u>>8), byte(u))
+			}
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("Unknown charset %s", charset)
+}
@@ -0,0 +1,114 @@
+package editor
+
+import "testing"
+
+func TestDecodeLineEndings(t *testing.T) {
+	testData := []struct {
+		content    string
+		expected   string
+		lineEnding string
+	}{
+		{"a\nb\n", "a\nb\n", "lf"},
+		{"a\r\nb\r\n", "a\nb\n", "crlf"},
+		{"a\rb\rc", "a\nb\nc", "cr"},
+		{"a\r\nb\r\nc\n", "a\nb\nc\n", "crlf"},
+		{"a\nb\nc\r\n", "a\nb\nc\n", "lf"},
+		{"", "", "lf"},
+	}
+
+	for _, data := range testData {
+		content, lineEnding := decodeLineEndings([]byte(data.content))
+		if string(content) != data.expected || lineEnding != data.lineEnding {
+			t.Errorf("%q: expected (%q, %s), found (%q, %s)\n", data.content,
+				data.expected, data.lineEnding, content, lineEnding)
+		}
+	}
+}
+
+func TestSetLineEnding(t *testing.T) {
+	b := newTestBuffer(t, "a.txt", "a\n")
+	e := b.parent
+	e.OpenBuffers = append(e.OpenBuffers, b)
+	e.CurrentBuffer = 1
+
+	e.ExecuteCommand("set-line-ending", 1)
+	e.Minibuffer.SetInput("lf")
+	e.Minibuffer.ConfirmAction()
+	if b.Modified {
+		t.Errorf("expected the same line ending not to modify the buffer\n")
+	}
+	e.ExecuteCommand("set-line-ending", 1)
+	e.Minibuffer.SetInput("crlf")
+	e.Minibuffer.ConfirmAction()
+	if b.LineEnding != "crlf" || !b.Modified {
+		t.Errorf("expected the buffer to be modified to save the conversion\n")
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	testData := []struct {
+		content  string
+		expected string
+		charset  string
+	}{
+		{"héllo", "héllo", "utf-8"},
+		{"\xef\xbb\xbfhi", "hi", "utf-8-bom"},
+		{"\xff\xfeh\x00\xe9\x00", "hé", "utf-16le"},
+		{"\xfe\xff\x00h\x00\xe9", "hé", "utf-16be"},
+		{"caf\xe9", "café", "latin1"},
+		{"", "", "utf-8"},
+	}
+
+	for _, data := range testData {
+		content, charset := decodeCharset([]byte(data.content))
+		if string(content) != data.expected || charset != data.charset {
+			t.Errorf("%q: expected (%q, %s), found (%q, %s)\n", data.content,
+				data.expected, data.charset, content, charset)
+		}
+		encoded, err := encodeContent(content, "lf", charset)
+		if err != nil || string(encoded) != data.content {
+			t.Errorf("%q: encoding back gave %q, %v\n", data.content, encoded, err)
+		}
+	}
+
+	if _, err := encodeContent([]byte("€"), "lf", "latin1"); err == nil {
+		t.Errorf("expected error encoding € as latin1\n")
+	}
+}
+
+func TestSetCodingSystem(t *testing.T) {
+	b := newTestBuffer(t, "a.txt", "a\n")
+	e := b.parent
+	e.OpenBuffers = append(e.OpenBuffers, b)
+	e.CurrentBuffer = 1
+
+	e.ExecuteCommand("set-buffer-file-coding-system", 1)
+	e.Minibuffer.SetInput("latin1")
+	e.Minibuffer.ConfirmAction()
+	if b.Charset != "latin1" || b.LineEnding != "lf" || !b.Modified {
+		t.Errorf("expected the buffer to be modified to save the new encoding\n")
+	}
+}
+
+func TestParseCodingSystem(t *testing.T) {
+	testData := []struct {
+		name       string
+		charset    string
+		lineEnding string
+		ok         bool
+	}{
+		{"utf-8", "utf-8", "", true},
+		{"Latin-1-dos", "latin1", "crlf", true},
+		{"utf-8-with-signature-unix", "utf-8-bom", "lf", true},
+		{"utf-16le", "utf-16le", "", true},
+		{"ebcdic", "", "", false},
+	}
+
+	for _, data := range testData {
+		charset, lineEnding, ok := parseCodingSystem(data.name)
+		if charset != data.charset || lineEnding != data.lineEnding || ok != data.ok {
+			t.Errorf("%q: expected (%s, %s, %v), found (%s, %s, %v)\n", data.name,
+				data.charset, data.lineEnding, data.ok, charset, lineEnding, ok)
+		}
+	}
+}
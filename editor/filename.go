@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// substituteFileName applies the emacs shortcuts to a typed file name: a
+// double slash starts over from the root and ~/ from the home directory, so
+// "/home/me/src//etc/hosts" is "/etc/hosts".
+func substituteFileName(input string) string {
+	if i := strings.LastIndex(input, "//"); i >= 0 {
+		input = input[i+1:]
+	}
+	if i := strings.LastIndex(input, "/~/"); i >= 0 {
+		input = input[i+1:]
+	}
+	return input
+}
+
+// expandFileName substitutes the shortcuts and expands a leading ~.
+func expandFileName(input string) string {
+	input = substituteFileName(input)
+	if input == "~" || strings.HasPrefix(input, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + input[1:]
+		}
+	}
+	return input
+}
+
+// abbreviateFileName replaces the home directory prefix of path with ~.
+func abbreviateFileName(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" || home == "/" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if strings.HasPrefix(path, home+"/") {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+// fileNameCandidates lists the entries of the directory part of input whose
+// name starts with the last path component. Directories end with a slash.
+func fileNameCandidates(input string) []string {
+	input = substituteFileName(input)
+	dir, prefix := input[:strings.LastIndex(input, "/")+1], input[strings.LastIndex(input, "/")+1:]
+	readDir := expandFileName(dir)
+	if readDir == "" {
+		readDir = "."
+	}
+
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+	candidates := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		} else if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(readDir, name)); err == nil && info.IsDir() {
+				name += "/"
+			}
+		}
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// completeFileName completes the last path component of input as far as it
+// is unambiguous. It returns the new input and, when more than one entry
+// matches, the candidates.
+func completeFileName(input string) (string, []string) {
+	input = substituteFileName(input)
+	candidates := fileNameCandidates(input)
+	if len(candidates) == 0 {
+		return input, nil
+	}
+	dir := input[:strings.LastIndex(input, "/")+1]
+	if len(candidates) == 1 {
+		return dir + candidates[0], nil
+	}
+	return dir + commonPrefix(candidates), candidates
+}
+
+func commonPrefix(strs []string) string {
+	if len(strs) == 0 {
+		return ""
+	}
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// defaultDirectory returns the directory of the current buffer's file, or its
+// Dir, or the working directory, abbreviated and ending with a slash.
+func (e *Editor) defaultDirectory() string {
+	dir := ""
+	if b := e.GetCurrentBuffer(); b != nil && b.Path != "" {
+		if abs, err := filepath.Abs(b.Path); err == nil {
+			dir = filepath.Dir(abs)
+		}
+	} else if b != nil {
+		dir = b.Dir
+	}
+	if dir == "" {
+		if wd, err := os.Getwd(); err == nil {
+			dir = wd
+		}
+	}
+	dir = abbreviateFileName(dir)
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	return dir
+}
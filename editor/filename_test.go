@@ -0,0 +1,80 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSubstituteFileName(t *testing.T) {
+	testData := []struct {
+		input    string
+		expected string
+	}{
+		{"/home/me/src/a.go", "/home/me/src/a.go"},
+		{"/home/me/src//etc/hosts", "/etc/hosts"},
+		{"/home/me/src/~/notes.txt", "~/notes.txt"},
+		{"~/src//tmp/~/x", "~/x"},
+		{"relative/path", "relative/path"},
+	}
+
+	for _, data := range testData {
+		if res := substituteFileName(data.input); res != data.expected {
+			t.Errorf("%q: expected %q, found %q\n", data.input, data.expected, res)
+		}
+	}
+}
+
+func TestCompleteFileName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "makefile", "model.go"} {
+		writeFile(t, filepath.Join(dir, name), "")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "misc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		input      string
+		expected   string
+		candidates []string
+	}{
+		{dir + "/mai", dir + "/main.go", nil},
+		{dir + "/ma", dir + "/ma", []string{"main.go", "makefile"}},
+		{dir + "/mi", dir + "/misc/", nil},
+		{dir + "/mo", dir + "/model.go", nil},
+		{dir + "/x", dir + "/x", nil},
+		{"/nowhere//" + dir[1:] + "/mak", dir + "/makefile", nil},
+	}
+
+	for _, data := range testData {
+		res, candidates := completeFileName(data.input)
+		if res != data.expected || !reflect.DeepEqual(candidates, data.candidates) {
+			t.Errorf("%q: expected (%q, %v), found (%q, %v)\n", data.input,
+				data.expected, data.candidates, res, candidates)
+		}
+	}
+}
+
+func TestFindNewFileNextToMatch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "a\n")
+
+	e := CreateEditor()
+	e.OpenBuffer()
+	e.Minibuffer.SetInput(dir + "/a")
+	e.Minibuffer.ConfirmAction()
+	if path := e.GetCurrentBuffer().Path; path != filepath.Join(dir, "a") {
+		t.Errorf("expected to visit the new file a, found %s\n", path)
+	}
+
+	e.OpenBuffer()
+	e.Minibuffer.SetInput(dir + "/a")
+	e.Minibuffer.SelectNext()
+	e.Minibuffer.ConfirmAction()
+	if path := e.GetCurrentBuffer().Path; path != filepath.Join(dir, "a.txt") {
+		t.Errorf("expected to visit the selected a.txt, found %s\n", path)
+	}
+}
@@ -0,0 +1,152 @@
+package editor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"org.example.goedit/utils"
+)
+
+// FORMAT_TIMEOUT is how long a formatter may run before it is killed
+const FORMAT_TIMEOUT = 10 * time.Second
+
+// format pipes the text through the formatter configured for the mode of the
+// buffer, if any, and replaces it with the output. A failing formatter leaves
+// the buffer unchanged and its first error line is returned, as does one
+// running longer than FORMAT_TIMEOUT.
+func (b *Buffer) format() error {
+	command := b.parent.Config.Formatters[b.Mode.Name]
+	if command == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), FORMAT_TIMEOUT)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	// the timeout also kills the programs started by the shell
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	if b.Path != "" {
+		cmd.Dir = filepath.Dir(absPath(b.Path))
+	}
+	cmd.Stdin = strings.NewReader(b.text(0, b.length()))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s: timed out after %s", command, FORMAT_TIMEOUT)
+		}
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return fmt.Errorf("%s: %s", command, msg)
+		}
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	b.applyText(stdout.String())
+	return nil
+}
+
+// applyText turns the content into text by replacing only the lines that
+// differ, as a single undo step. The cursor and the mark stay on the same
+// text, at the same column of their line when that line was changed.
+func (b *Buffer) applyText(text string) {
+	markActive := b.markActive
+	b.markActive = false
+	anchors := []*anchor{newAnchor(b.gapStart, b.length(), text), newAnchor(b.markPos, b.length(), text)}
+	removed, inserted := 0, 0
+
+	edits := utils.DiffLines(strings.SplitAfter(b.text(0, b.length()), "\n"), strings.SplitAfter(text, "\n"))
+	b.undo.BeginGroup()
+	pos, oldPos := 0, 0
+	for _, edit := range edits {
+		n := len(edit.Line)
+		switch edit.Op {
+		case ' ':
+			for _, a := range anchors {
+				a.kept(pos, oldPos, n)
+			}
+			removed, inserted = 0, 0
+			pos += n
+			oldPos += n
+		case '-':
+			if n == 0 {
+				continue
+			}
+			for _, a := range anchors {
+				a.removed(oldPos, n, removed)
+			}
+			removed += 1
+			b.moveTo(pos)
+			for i := 0; i < n; i++ {
+				b.DeleteAfter(true)
+			}
+			oldPos += n
+		case '+':
+			for _, a := range anchors {
+				a.inserted(pos, edit.Line, inserted)
+			}
+			inserted += 1
+			b.moveTo(pos)
+			b.Insert(edit.Line, true)
+			pos += n
+		}
+	}
+	b.undo.EndGroup()
+	b.markPos = min(anchors[1].position(pos), b.length())
+	b.markActive = markActive
+	b.moveTo(min(anchors[0].position(pos), b.length()))
+}
+
+// anchor follows a position while applyText replaces lines. A removed line
+// holding it is matched with the inserted line at the same index of the change.
+type anchor struct {
+	old   int
+	new   int // -1 until known
+	col   int // column in a removed line, -1 when not pending
+	index int // index of that line in its change
+}
+
+func newAnchor(old int, length int, text string) *anchor {
+	a := &anchor{old: old, new: -1, col: -1}
+	if old == length {
+		a.new = len(text)
+	}
+	return a
+}
+
+func (a *anchor) kept(pos int, oldPos int, n int) {
+	if a.col >= 0 {
+		a.new, a.col = pos, -1
+	}
+	if a.old >= oldPos && a.old < oldPos+n {
+		a.new = pos + a.old - oldPos
+	}
+}
+
+func (a *anchor) removed(oldPos int, n int, index int) {
+	if a.old >= oldPos && a.old < oldPos+n {
+		a.col, a.index = a.old-oldPos, index
+	}
+}
+
+func (a *anchor) inserted(pos int, line string, index int) {
+	if a.col >= 0 && index == a.index {
+		a.new, a.col = pos+min(a.col, len(strings.TrimSuffix(line, "\n"))), -1
+	}
+}
+
+// position returns the new position, end when the anchor was not placed.
+func (a *anchor) position(end int) int {
+	if a.new < 0 {
+		return end
+	}
+	return a.new
+}
@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyText(t *testing.T) {
+	testData := []struct {
+		content  string
+		cursor   int
+		text     string
+		expected int
+	}{
+		{"a\nb\nc\n", 4, "a\nb\nc\nd\n", 4},    // unchanged line
+		{"a\nb\nc\n", 4, "x\ny\na\nb\nc\n", 8}, // lines inserted before
+		{"a\nbxxx\nc\n", 5, "a\nb x\nc\n", 5},  // changed line keeps the column
+		{"a\nbxxx\nc\n", 6, "a\nb\nc\n", 3},    // column clamped to the new line
+		{"a\nb\nc\n", 2, "a\nc\n", 2},          // removed line moves to the next
+		{"a\nb\nc", 5, "a\nb\nc\n", 6},         // end of buffer stays at the end
+		{"func f(){\nx\n}\n", 10, "func f() {\n\tx\n}\n", 11},
+	}
+	for _, data := range testData {
+		b := newTestBuffer(t, "a.go", data.content)
+		b.moveTo(data.cursor)
+		b.applyText(data.text)
+		if res := b.text(0, b.length()); res != data.text {
+			t.Errorf("%q: expected %q, found %q\n", data.content, data.text, res)
+		}
+		if b.gapStart != data.expected {
+			t.Errorf("%q -> %q: expected the cursor at %d, found %d\n", data.content, data.text, data.expected, b.gapStart)
+		}
+		b.Undo()
+		if res := b.text(0, b.length()); res != data.content {
+			t.Errorf("%q: expected a single undo to restore the text, found %q\n", data.content, res)
+		}
+	}
+}
+
+func TestApplyTextKeepsRegion(t *testing.T) {
+	b := newTestBuffer(t, "a.go", "package main\n\nfunc main() {\n}\n")
+	b.moveTo(13)
+	b.ToggleMark()
+	b.moveTo(27)
+	b.applyText("package main\n\nimport \"fmt\"\n\nfunc main() {\n}\n")
+	expected := "package main\n\nimport \"fmt\"\n\nfunc main() {\n}\n"
+	if res := b.text(0, b.length()); res != expected {
+		t.Fatalf("expected only the import to be added, found %q\n", res)
+	}
+	if !b.IsMarkActive() || b.markPos != 13 || b.gapStart != 41 {
+		t.Errorf("expected the region on the same text, found %d-%d active %v\n", b.markPos, b.gapStart, b.IsMarkActive())
+	}
+}
+
+func TestFormatOnSave(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	writeFile(t, path, "package main\n")
+
+	e := CreateEditor()
+	e.Config.Formatters["go"] = "tr a-z A-Z"
+	b := e.FindFile(path)
+	b.MoveEndFile()
+	b.Insert("var x\n", true)
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "PACKAGE MAIN\nVAR X\n" {
+		t.Errorf("expected the formatted text to be saved, found %q\n", data)
+	}
+
+	e.Config.Formatters["go"] = "echo '<standard input>:2:5: expected type' >&2; exit 2"
+	b.Insert("broken\n", true)
+	err := b.Save()
+	if err == nil || !strings.Contains(err.Error(), "2:5: expected type") {
+		t.Errorf("expected the formatter error, found %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "PACKAGE MAIN\nVAR X\n" || !b.Modified {
+		t.Errorf("expected the file not to be saved, found %q\n", data)
+	}
+}
@@ -0,0 +1,212 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"org.example.goedit/utils"
+)
+
+const GREP_BUFFER = "*grep*"
+
+var GrepMode = &Mode{
+	Name: "grep",
+	Keys: map[string]string{
+		"RET": "compile-goto-error",
+		"g":   "grep-rerun",
+	},
+}
+
+// grepSearch is the search running, or last run, in *grep*.
+type grepSearch struct {
+	pattern   string
+	dir       string
+	matches   int
+	process   *os.Process // the rg process, if rg is used
+	cancelled atomic.Bool
+}
+
+// Grep searches the project of the current buffer for a regular expression.
+func (e *Editor) Grep() {
+	dir := projectRoot(expandFileName(e.defaultDirectory()))
+	e.readMinibuffer("Grep in "+abbreviateFileName(dir)+"/ for: ", "", nil, HISTORY_SEARCH, func(pattern string) {
+		e.startGrep(pattern, dir)
+	})
+}
+
+// Rgrep searches a directory given after the regular expression.
+func (e *Editor) Rgrep() {
+	e.readMinibuffer("Search for: ", "", nil, HISTORY_SEARCH, func(pattern string) {
+		e.ReadFileName("In directory: ", func(dir string) {
+			e.startGrep(pattern, absPath(dir))
+		})
+	})
+}
+
+// GrepRerun runs the last search again.
+func (e *Editor) GrepRerun() {
+	if e.grep == nil {
+		e.Grep()
+		return
+	}
+	e.startGrep(e.grep.pattern, e.grep.dir)
+}
+
+// projectRoot returns the closest directory above dir holding a .git, or dir
+// itself.
+func projectRoot(dir string) string {
+	dir = absPath(dir)
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if d == filepath.Dir(d) {
+			return dir
+		}
+	}
+}
+
+// startGrep lists the lines of the files below dir matching pattern in
+// *grep*, as path:line:col:text lines that next-error visits. It uses rg
+// when it is installed, otherwise walks the tree skipping what .gitignore
+// files ignore. A running search is stopped.
+func (e *Editor) startGrep(pattern string, dir string) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		e.Minibuffer.SetMessage("Invalid regexp: " + err.Error())
+		return
+	}
+	if e.grep != nil {
+		e.grep.cancelled.Store(true)
+		if e.grep.process != nil {
+			killProcess(e.grep.process)
+		}
+	}
+	g := &grepSearch{pattern: pattern, dir: dir}
+	e.grep = g
+
+	b := e.specialBuffer(GREP_BUFFER)
+	b.Mode = GrepMode
+	b.Dir = dir
+	b.errors = newErrorList(dir)
+	b.highlights = make(map[int][][2]int)
+	e.errorBuffer = b
+	header := "-*- mode: grep; default-directory: \"" + abbreviateFileName(dir) + "/\" -*-\n"
+	e.showBuffer(b)
+
+	onLine := func(line string) {
+		if e.grep == g {
+			e.grepOutput(b, re, line)
+		}
+	}
+	onExit := func(err error) {
+		if e.grep == g {
+			g.process = nil
+			e.grepFinished(b, err)
+		}
+	}
+
+	if rg, err := exec.LookPath("rg"); err == nil {
+		b.setText(header + "rg -n --column -e " + strconv.Quote(pattern) + "\n\n")
+		b.errors.row = b.LineCount() - 1
+		cmd := exec.Command(rg, "--line-number", "--column", "--no-heading", "--color=never", "-e", pattern, ".")
+		cmd.Dir = dir
+		if err := e.startProcess(cmd, onLine, onExit); err != nil {
+			b.appendText("Error running rg: " + err.Error() + "\n")
+			return
+		}
+		g.process = cmd.Process
+		return
+	}
+
+	b.setText(header + "Searching for " + strconv.Quote(pattern) + "\n\n")
+	b.errors.row = b.LineCount() - 1
+	go func() {
+		err := grepTree(dir, re, &g.cancelled, func(line string) {
+			e.Post(func() { onLine(line) })
+		})
+		e.Post(func() { onExit(err) })
+	}()
+}
+
+// grepTree calls emit with a path:line:col:text line for each match of re in
+// the text files below dir, skipping .git and the ignored files. It stops
+// early once cancelled is set.
+func grepTree(dir string, re *regexp.Regexp, cancelled *atomic.Bool, emit func(line string)) error {
+	var ignore utils.Gitignore
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if cancelled.Load() {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			// unreadable entries are skipped like rg does
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if rel == "." {
+				rel = ""
+			} else if entry.Name() == ".git" || ignore.Ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if content, err := os.ReadFile(filepath.Join(path, ".gitignore")); err == nil {
+				ignore.Add(rel, string(content))
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || ignore.Ignored(rel, false) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+			// binary files are skipped
+			return nil
+		}
+		for i, line := range strings.Split(string(content), "\n") {
+			if loc := re.FindStringIndex(line); loc != nil {
+				emit(rel + ":" + strconv.Itoa(i+1) + ":" + strconv.Itoa(loc[0]+1) + ":" + line + "\n")
+			}
+		}
+		return nil
+	})
+}
+
+// grepOutput appends a line of results, recording its location and the
+// matches to highlight in its text.
+func (e *Editor) grepOutput(b *Buffer, re *regexp.Regexp, line string) {
+	row := b.errors.appendLine(b, line)
+	if prefix := errorRegexp.FindStringIndex(line); prefix != nil {
+		e.grep.matches += 1
+		text := strings.TrimSuffix(line[prefix[1]:], "\n")
+		for _, match := range re.FindAllStringIndex(text, -1) {
+			b.highlights[row] = append(b.highlights[row], [2]int{prefix[1] + match[0], prefix[1] + match[1]})
+		}
+	}
+}
+
+func (e *Editor) grepFinished(b *Buffer, err error) {
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		// rg exits with 1 when nothing matched
+		b.appendText("\nGrep failed: " + err.Error() + "\n")
+		e.Minibuffer.SetMessage("Grep failed")
+		return
+	}
+	status := "Grep finished with no matches found"
+	if e.grep.matches == 1 {
+		status = "Grep finished with 1 match found"
+	} else if e.grep.matches > 1 {
+		status = "Grep finished with " + strconv.Itoa(e.grep.matches) + " matches found"
+	}
+	b.appendText("\n" + status + "\n")
+	e.Minibuffer.SetMessage(status)
+}
@@ -0,0 +1,72 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGrepTree(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "build"), 0755)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\nbuild/\n")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {\n\tfoo(1)\n}\n")
+	writeFile(t, filepath.Join(dir, "app.log"), "foo\n")
+	writeFile(t, filepath.Join(dir, "build", "out.txt"), "foo\n")
+	writeFile(t, filepath.Join(dir, "sub", ".gitignore"), "skip.txt\n")
+	writeFile(t, filepath.Join(dir, "sub", "skip.txt"), "foo\n")
+	writeFile(t, filepath.Join(dir, "sub", "notes.txt"), "a foo and a foo\n")
+	writeFile(t, filepath.Join(dir, "image.bin"), "foo\x00\n")
+
+	var lines []string
+	var cancelled atomic.Bool
+	err := grepTree(dir, regexp.MustCompile("foo"), &cancelled, func(line string) {
+		lines = append(lines, line)
+	})
+	sort.Strings(lines)
+	expected := "main.go:4:2:\tfoo(1)\n|sub/notes.txt:1:3:a foo and a foo\n"
+	if err != nil || strings.Join(lines, "|") != expected {
+		t.Errorf("expected %q, found %q %v\n", expected, strings.Join(lines, "|"), err)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, ".git"), 0755)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	writeFile(t, filepath.Join(dir, "sub", "notes.txt"), "one\na foo and a foo\n")
+
+	e := CreateEditor()
+	e.FindFile(filepath.Join(dir, "sub"))
+	e.Grep()
+	e.Minibuffer.SetInput("fo+")
+	e.Minibuffer.ConfirmAction()
+	b := e.GetCurrentBuffer()
+	if b.Name != GREP_BUFFER || b.Dir != dir {
+		t.Fatalf("expected to search the project root, found %s in %s\n", b.Name, b.Dir)
+	}
+	runEvents(t, e, func() bool { return strings.Contains(b.text(0, b.length()), "Grep finished") })
+
+	if len(b.errors.locations) != 1 {
+		t.Fatalf("expected a match, found %q\n", b.text(0, b.length()))
+	}
+	row := b.errors.locations[0].row
+	line := b.text(b.rowStart(row), b.lineEnd(b.rowStart(row)))
+	highlights := b.Highlights(row)
+	if len(highlights) != 2 || line[highlights[1][0]:highlights[1][1]] != "foo" {
+		t.Errorf("expected both matches highlighted in %q, found %v\n", line, highlights)
+	}
+
+	b.GotoLine(row)
+	e.GotoError()
+	target := e.GetCurrentBuffer()
+	if target.Path != filepath.Join(dir, "sub", "notes.txt") || target.gapStart != 6 {
+		t.Errorf("expected notes.txt at 2:3, found %s at %d\n", target.Path, target.gapStart)
+	}
+}
@@ -0,0 +1,106 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// kinds of minibuffer history, each prompt uses the ring of its kind
+const (
+	HISTORY_FILES    = "files"
+	HISTORY_SEARCH   = "search"
+	HISTORY_COMMANDS = "commands"
+	HISTORY_SHELL    = "shell"
+	HISTORY_COMPILE  = "compile"
+	HISTORY_RECENTF  = "recentf" // files visited, not typed inputs
+	HISTORY_SIZE     = 100
+)
+
+// History keeps the inputs of minibuffer prompts, persisted in a file with
+// one `<kind> "<quoted input>"` line per element, oldest first.
+type History struct {
+	path  string
+	rings map[string][]string
+}
+
+// LoadHistory reads the history file at path. A missing file results in an
+// empty history.
+func LoadHistory(path string) (*History, error) {
+	h := &History{
+		path:  path,
+		rings: make(map[string][]string),
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return h, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		kind, quoted, found := strings.Cut(scanner.Text(), " ")
+		if !found {
+			continue
+		}
+		if input, err := strconv.Unquote(quoted); err == nil {
+			h.rings[kind] = append(h.rings[kind], input)
+		}
+	}
+	return h, scanner.Err()
+}
+
+// Items returns the history of kind, oldest first.
+func (h *History) Items(kind string) []string {
+	return h.rings[kind]
+}
+
+// Add appends input to the history of kind, removing an older copy of it,
+// and saves the history file.
+func (h *History) Add(kind string, input string) error {
+	if kind == "" || input == "" {
+		return nil
+	}
+	ring := make([]string, 0, len(h.rings[kind])+1)
+	for _, item := range h.rings[kind] {
+		if item != input {
+			ring = append(ring, item)
+		}
+	}
+	ring = append(ring, input)
+	if len(ring) > HISTORY_SIZE {
+		ring = ring[len(ring)-HISTORY_SIZE:]
+	}
+	h.rings[kind] = ring
+	return h.save()
+}
+
+// Remove deletes input from the history of kind and saves the history file.
+func (h *History) Remove(kind string, input string) error {
+	ring := h.rings[kind][:0]
+	for _, item := range h.rings[kind] {
+		if item != input {
+			ring = append(ring, item)
+		}
+	}
+	h.rings[kind] = ring
+	return h.save()
+}
+
+func (h *History) save() error {
+	var sb strings.Builder
+	for kind, ring := range h.rings {
+		for _, item := range ring {
+			sb.WriteString(kind + " " + strconv.Quote(item) + "\n")
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, []byte(sb.String()), 0644)
+}
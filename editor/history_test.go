@@ -0,0 +1,57 @@
+package editor
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{"a.go", "b.go", "a.go", "with \"quotes\""} {
+		if err := h.Add(HISTORY_FILES, input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Add(HISTORY_COMMANDS, "tabify")
+	h.Add(HISTORY_COMMANDS, "")
+
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"b.go", "a.go", "with \"quotes\""}
+	if !reflect.DeepEqual(loaded.Items(HISTORY_FILES), expected) {
+		t.Errorf("expected files history %v, found %v\n", expected, loaded.Items(HISTORY_FILES))
+	}
+	if !reflect.DeepEqual(loaded.Items(HISTORY_COMMANDS), []string{"tabify"}) {
+		t.Errorf("unexpected commands history %v\n", loaded.Items(HISTORY_COMMANDS))
+	}
+}
+
+func TestMinibufferHistory(t *testing.T) {
+	m := NewMinibuffer()
+	m.setHistory([]string{"make", "go build", "go test"})
+	m.SetInput("go")
+
+	m.HistoryPrevious()
+	m.HistoryPrevious()
+	if m.input != "go build" {
+		t.Errorf("expected go build, found %q\n", m.input)
+	}
+	m.HistoryNext()
+	m.HistoryNext()
+	if m.input != "go" {
+		t.Errorf("expected typed input back, found %q\n", m.input)
+	}
+
+	m.SetInput("ma")
+	m.HistorySearch()
+	if m.input != "make" {
+		t.Errorf("expected make, found %q\n", m.input)
+	}
+}
@@ -0,0 +1,241 @@
+package editor
+
+import (
+	"strings"
+
+	"org.example.goedit/utils"
+)
+
+func isBlank(ch byte) bool {
+	return ch == ' ' || ch == '\t'
+}
+
+// indentEnd returns the position of the first non blank byte of the line
+// starting at start.
+func (b *Buffer) indentEnd(start int) int {
+	pos := start
+	for pos < b.length() && isBlank(b.byteAt(pos)) {
+		pos += 1
+	}
+	return pos
+}
+
+// computeIndent returns the indentation the line starting at start should
+// have. It copies the indentation of the previous non blank line and, in modes
+// with block indentation, adds a level after an opening bracket and removes
+// one when the line starts with a closing bracket.
+func (b *Buffer) computeIndent(start int) string {
+	indent := ""
+	var last byte
+	for pos := start; pos > 0; {
+		prevStart := b.lineStart(pos - 1)
+		prevIndentEnd := b.indentEnd(prevStart)
+		if prevIndentEnd < pos-1 {
+			indent = b.text(prevStart, prevIndentEnd)
+			for i := pos - 2; i >= prevIndentEnd; i-- {
+				if !isBlank(b.byteAt(i)) {
+					last = b.byteAt(i)
+					break
+				}
+			}
+			break
+		}
+		pos = prevStart
+	}
+
+	if b.Mode.BlockIndent {
+		levels := 0
+		if isOpener(last) {
+			levels += 1
+		}
+		first := b.indentEnd(start)
+		if first < b.length() && isCloser(b.byteAt(first)) {
+			levels -= 1
+		}
+		if levels != 0 {
+			col := utils.Tlen(indent, b.TabWidth) + levels*b.indentColumns()
+			indent = b.indentString(max(col, 0))
+		}
+	}
+	return indent
+}
+
+// indentColumns returns the width of one level of indentation.
+func (b *Buffer) indentColumns() int {
+	if b.IndentWithSpaces {
+		return b.IndentWidth
+	}
+	return b.TabWidth
+}
+
+// indentString builds an indentation that spans col columns, using tabs
+// unless the buffer indents with spaces.
+func (b *Buffer) indentString(col int) string {
+	if b.IndentWithSpaces {
+		return strings.Repeat(" ", col)
+	}
+	return strings.Repeat("\t", col/b.TabWidth) + strings.Repeat(" ", col%b.TabWidth)
+}
+
+// setIndentation replaces the indentation of the line starting at start with
+// indent, keeping the cursor on the same text.
+func (b *Buffer) setIndentation(start int, indent string) {
+	end := b.indentEnd(start)
+	if b.text(start, end) == indent {
+		return
+	}
+	cursor := b.gapStart
+	b.moveTo(start)
+	for i := start; i < end; i++ {
+		b.DeleteAfter(true)
+	}
+	b.Insert(indent, true)
+
+	newEnd := start + len(indent)
+	if cursor >= end {
+		cursor += newEnd - end
+	} else if cursor >= start {
+		cursor = newEnd
+	}
+	b.moveTo(cursor)
+}
+
+// Newline breaks the line at the cursor and indents the new line. Blanks
+// around the cursor are removed. Undo removes the whole change at once.
+func (b *Buffer) Newline() {
+	if b.readOnly() {
+		return
+	}
+	if b.markActive {
+		b.deleteToMark()
+	}
+	b.undo.BeginGroup()
+	defer b.undo.EndGroup()
+
+	for b.gapEnd < len(b.content) && isBlank(b.content[b.gapEnd]) {
+		b.DeleteAfter(true)
+	}
+	for b.gapStart > 0 && isBlank(b.content[b.gapStart-1]) {
+		b.DeleteBefore()
+	}
+	b.Insert("\n", true)
+	b.setIndentation(b.gapStart, b.computeIndent(b.gapStart))
+}
+
+// SelfInsert inserts a typed string. In modes with block indentation, a
+// closing bracket typed in the indentation re-indents its line.
+func (b *Buffer) SelfInsert(str string) {
+	if b.readOnly() {
+		return
+	}
+	start := b.lineStart(b.gapStart)
+	electric := b.Mode.BlockIndent && len(str) == 1 && isCloser(str[0]) &&
+		!b.markActive && b.indentEnd(start) >= b.gapStart
+	if !electric {
+		b.Insert(str, true)
+		return
+	}
+
+	b.undo.BeginGroup()
+	defer b.undo.EndGroup()
+	b.Insert(str, true)
+	b.setIndentation(start, b.computeIndent(start))
+}
+
+// IndentRegion re-indents every line of the region as a single undo step.
+func (b *Buffer) IndentRegion() {
+	b.forRegionLines(func(start int) {
+		if b.indentEnd(start) == b.lineEnd(start) {
+			b.setIndentation(start, "")
+		} else {
+			b.setIndentation(start, b.computeIndent(start))
+		}
+	})
+}
+
+// IndentRigidly shifts every non blank line of the region by count columns,
+// to the left when count is negative, as a single undo step.
+func (b *Buffer) IndentRigidly(count int) {
+	b.forRegionLines(func(start int) {
+		end := b.indentEnd(start)
+		if end == b.lineEnd(start) {
+			return
+		}
+		col := max(utils.Tlen(b.text(start, end), b.TabWidth)+count, 0)
+		b.setIndentation(start, b.indentString(col))
+	})
+}
+
+// InsertTab inserts a tab, or spaces up to the next indentation stop when the
+// buffer indents with spaces.
+func (b *Buffer) InsertTab() {
+	if b.readOnly() {
+		return
+	}
+	if !b.IndentWithSpaces {
+		b.Insert("\t", true)
+		return
+	}
+	start := b.lineStart(b.gapStart)
+	col := utils.Tlen(b.text(start, b.gapStart), b.TabWidth)
+	b.Insert(strings.Repeat(" ", b.IndentWidth-col%b.IndentWidth), true)
+}
+
+// Tabify converts runs of spaces to tabs in the lines of the region.
+func (b *Buffer) Tabify() {
+	b.forRegionLines(func(start int) {
+		b.replaceLine(start, utils.Tabify(b.text(start, b.lineEnd(start)), b.TabWidth))
+	})
+}
+
+// Untabify expands the tabs in the lines of the region to spaces.
+func (b *Buffer) Untabify() {
+	b.forRegionLines(func(start int) {
+		b.replaceLine(start, utils.Texp(b.text(start, b.lineEnd(start)), b.TabWidth))
+	})
+}
+
+// replaceLine replaces the text of the line starting at start. A cursor on
+// that line keeps its offset, as far as the new line allows.
+func (b *Buffer) replaceLine(start int, line string) {
+	end := b.lineEnd(start)
+	if b.text(start, end) == line {
+		return
+	}
+	cursor := b.gapStart
+	b.moveTo(start)
+	for i := start; i < end; i++ {
+		b.DeleteAfter(true)
+	}
+	b.Insert(line, true)
+
+	if cursor > end {
+		cursor += len(line) - (end - start)
+	} else if cursor >= start {
+		cursor = min(cursor, start+len(line))
+	}
+	b.moveTo(cursor)
+}
+
+// forRegionLines calls fn with the start position of each line in the region,
+// top to bottom, grouping all the changes in one undo step. fn must only
+// change the line it is given.
+func (b *Buffer) forRegionLines(fn func(start int)) {
+	if b.readOnly() {
+		return
+	}
+	first, last, ok := b.RegionRows()
+	if !ok {
+		b.parent.Minibuffer.SetMessage("The mark is not set now")
+		return
+	}
+	b.markActive = false
+	b.undo.BeginGroup()
+	defer b.undo.EndGroup()
+
+	start := b.rowStart(first)
+	for row := first; row <= last; row++ {
+		fn(start)
+		start = b.lineEnd(start) + 1
+	}
+}
@@ -0,0 +1,107 @@
+package editor
+
+import "testing"
+
+// newTestBuffer returns a buffer of a new editor, with its own configuration
+// directory. The editor is the parent of the buffer.
+func newTestBuffer(t *testing.T, name string, content string) *Buffer {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	e := CreateEditor()
+	return NewBuffer(e, name, []byte(content), false)
+}
+
+func TestNewlineIndent(t *testing.T) {
+	testData := []struct {
+		name     string
+		content  string
+		cursor   int
+		expected string
+	}{
+		{"a.go", "\tfoo()", 6, "\tfoo()\n\t"},
+		{"a.go", "func() {", 8, "func() {\n\t"},
+		{"a.go", "func() {}", 8, "func() {\n}"},
+		{"a.go", "\tif x {   ", 10, "\tif x {\n\t\t"},
+		{"a.txt", "  text {", 8, "  text {\n  "},
+	}
+
+	for _, data := range testData {
+		b := newTestBuffer(t, data.name, data.content)
+		b.moveTo(data.cursor)
+		b.Newline()
+		if res := b.text(0, b.length()); res != data.expected {
+			t.Errorf("%q: expected %q, found %q\n", data.content, data.expected, res)
+		}
+		b.Undo()
+		if res := b.text(0, b.length()); res != data.content {
+			t.Errorf("%q: undo left %q\n", data.content, res)
+		}
+	}
+}
+
+func TestElectricCloser(t *testing.T) {
+	b := newTestBuffer(t, "a.go", "if x {\n\t\t")
+	b.MoveEndFile()
+	b.SelfInsert("}")
+	if res := b.text(0, b.length()); res != "if x {\n}" {
+		t.Errorf("expected dedented closer, found %q\n", res)
+	}
+}
+
+func TestIndentRegion(t *testing.T) {
+	content := "func f() {\nx := 1\n   if x {\ny()\n\n        }\n}"
+	expected := "func f() {\n\tx := 1\n\tif x {\n\t\ty()\n\n\t}\n}"
+	b := newTestBuffer(t, "a.go", content)
+	// the fixture is indented with spaces, the result with tabs
+	b.IndentWithSpaces, b.IndentWidth = false, TABSIZE
+	b.ToggleMark()
+	b.MoveEndFile()
+	b.IndentRegion()
+	if res := b.text(0, b.length()); res != expected {
+		t.Errorf("expected %q, found %q\n", expected, res)
+	}
+	b.Undo()
+	if res := b.text(0, b.length()); res != content {
+		t.Errorf("undo should restore the region in one step, found %q\n", res)
+	}
+}
+
+func TestIndentRigidly(t *testing.T) {
+	b := newTestBuffer(t, "a.txt", "a\n b\n\nc\nd")
+	b.ToggleMark()
+	b.GotoLine(4)
+	b.IndentRigidly(3)
+	if res := b.text(0, b.length()); res != "\t a\n\t\tb\n\n\t c\nd" {
+		t.Errorf("unexpected result %q\n", res)
+	}
+}
+
+func TestIndentWithSpaces(t *testing.T) {
+	b := newTestBuffer(t, "a.js", "if (x) {\n    a();\n    b();\n}")
+	if !b.IndentWithSpaces || b.IndentWidth != 4 {
+		t.Fatalf("expected 4 spaces indentation, found %v %d\n", b.IndentWithSpaces, b.IndentWidth)
+	}
+	b.GotoLine(2)
+	b.MoveEndLine()
+	b.SelfInsert("{")
+	b.Newline()
+	b.InsertTab()
+	if res := b.text(0, b.length()); res != "if (x) {\n    a();\n    b();{\n            \n}" {
+		t.Errorf("unexpected result %q\n", res)
+	}
+}
+
+func TestTabifyRegion(t *testing.T) {
+	b := newTestBuffer(t, "a.txt", "\ta\n\t\tb")
+	b.ToggleMark()
+	b.MoveEndFile()
+	b.Untabify()
+	if res := b.text(0, b.length()); res != "  a\n    b" {
+		t.Errorf("unexpected untabify result %q\n", res)
+	}
+	b.ToggleMark()
+	b.MoveStartFile()
+	b.Tabify()
+	if res := b.text(0, b.length()); res != "\ta\n\t\tb" {
+		t.Errorf("unexpected tabify result %q\n", res)
+	}
+}
@@ -0,0 +1,138 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lockPath returns the emacs style lock of path, a .#name symbolic link in
+// the same directory pointing to user@host.pid.
+func lockPath(path string) string {
+	dir, name := filepath.Split(path)
+	return filepath.Join(dir, ".#"+name)
+}
+
+func lockOwner() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s.%d", name, host, os.Getpid())
+}
+
+// parseLockOwner splits the target of a lock link, user@host.pid optionally
+// followed by :boot-time, into its host and pid.
+func parseLockOwner(owner string) (string, int, bool) {
+	owner, _, _ = strings.Cut(owner, ":")
+	_, hostPid, found := strings.Cut(owner, "@")
+	dot := strings.LastIndex(hostPid, ".")
+	if !found || dot < 0 {
+		return "", 0, false
+	}
+	pid, err := strconv.Atoi(hostPid[dot+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return hostPid[:dot], pid, true
+}
+
+// lockedBy returns the owner of the lock of path when another process holds
+// it. A lock left by a process of this host that no longer runs is removed.
+func lockedBy(path string) string {
+	owner, err := os.Readlink(lockPath(path))
+	if err != nil || owner == lockOwner() {
+		return ""
+	}
+	host, pid, ok := parseLockOwner(owner)
+	if ok {
+		if localhost, _ := os.Hostname(); host == localhost && syscall.Kill(pid, 0) == syscall.ESRCH {
+			os.Remove(lockPath(path))
+			return ""
+		}
+	}
+	return owner
+}
+
+// describeLockOwner formats a lock owner as user@host (pid N).
+func describeLockOwner(owner string) string {
+	owner, _, _ = strings.Cut(owner, ":")
+	dot := strings.LastIndex(owner, ".")
+	if _, _, ok := parseLockOwner(owner); !ok || dot < 0 {
+		return owner
+	}
+	return owner[:dot] + " (pid " + owner[dot+1:] + ")"
+}
+
+// lock takes the lock of the file of b, unless another process holds it.
+func (b *Buffer) lock() error {
+	if !b.locking || b.locked {
+		return nil
+	}
+	if owner := lockedBy(b.Path); owner != "" {
+		return fmt.Errorf("%s is locked by %s", b.Name, describeLockOwner(owner))
+	}
+	err := os.Symlink(lockOwner(), lockPath(b.Path))
+	if errors.Is(err, os.ErrExist) {
+		// another process may have taken the lock since lockedBy
+		owner, err := os.Readlink(lockPath(b.Path))
+		if err != nil {
+			return err
+		}
+		if owner != lockOwner() {
+			return fmt.Errorf("%s is locked by %s", b.Name, describeLockOwner(owner))
+		}
+	} else if err != nil {
+		return err
+	}
+	b.locked = true
+	return nil
+}
+
+// stealLock takes the lock of the file of b from whoever holds it.
+func (b *Buffer) stealLock() error {
+	if err := os.Remove(lockPath(b.Path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	b.locked = false
+	return b.lock()
+}
+
+// unlock releases the lock of the file of b, if it holds it.
+func (b *Buffer) unlock() {
+	if !b.locked {
+		return
+	}
+	if owner, err := os.Readlink(lockPath(b.Path)); err == nil && owner == lockOwner() {
+		os.Remove(lockPath(b.Path))
+	}
+	b.locked = false
+}
+
+// checkLock asks what to do when the file of b, just visited, is locked by
+// another process: s steals the lock, p proceeds without it and q kills the
+// buffer.
+func (e *Editor) checkLock(b *Buffer) {
+	owner := lockedBy(b.Path)
+	if owner == "" {
+		return
+	}
+	e.Minibuffer.Choose(b.Name+" locked by "+describeLockOwner(owner)+": (s, q, p)? ", "sqp", func(choice byte) {
+		switch choice {
+		case 's':
+			if err := b.stealLock(); err != nil {
+				e.Minibuffer.SetMessage("Error stealing lock: " + err.Error())
+			}
+		case 'p':
+			b.locking = false
+		case 'q':
+			e.closeBuffer(b)
+		}
+	}, nil)
+}
@@ -0,0 +1,102 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockWhileModified(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	lock := filepath.Join(dir, ".#notes.txt")
+	writeFile(t, path, "one\n")
+
+	e := CreateEditor()
+	b := e.FindFile(path)
+	if _, err := os.Lstat(lock); err == nil {
+		t.Fatalf("expected no lock before the buffer is modified\n")
+	}
+	b.Insert("x", true)
+	if owner, err := os.Readlink(lock); err != nil || owner != lockOwner() {
+		t.Fatalf("expected a lock owned by this process, found %q %v\n", owner, err)
+	}
+	if err := b.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(lock); err == nil {
+		t.Errorf("expected saving to remove the lock\n")
+	}
+}
+
+func TestLockedByOther(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	lock := filepath.Join(dir, ".#notes.txt")
+	writeFile(t, path, "one\n")
+	// pid 1 always runs, the lock is not stale
+	other := "someone@" + hostname(t) + ".1:12345"
+	if err := os.Symlink(other, lock); err != nil {
+		t.Fatal(err)
+	}
+
+	e := CreateEditor()
+	e.FindFile(path)
+	if line := e.Minibuffer.GetLine(); line != path+" locked by someone@"+hostname(t)+" (pid 1): (s, q, p)? " {
+		t.Fatalf("unexpected question %q\n", line)
+	}
+	e.Minibuffer.InsertAtCol("q")
+	if e.findBuffer(path) >= 0 {
+		t.Fatalf("expected q to kill the buffer\n")
+	}
+
+	b := e.FindFile(path)
+	e.Minibuffer.InsertAtCol("s")
+	if owner, _ := os.Readlink(lock); owner != lockOwner() || !b.locked {
+		t.Errorf("expected the lock to be stolen, found %q\n", owner)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	// pids are below 2^22 on linux
+	stale := fmt.Sprintf("someone@%s.%d", hostname(t), 1<<23)
+	if err := os.Symlink(stale, lockPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	if owner := lockedBy(path); owner != "" {
+		t.Errorf("expected a stale lock to be ignored, found %q\n", owner)
+	}
+}
+
+func TestLockAlreadyTaken(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	b := &Buffer{Name: path, Path: path, locking: true}
+	if err := os.Symlink(lockOwner(), lockPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.lock(); err != nil || !b.locked {
+		t.Errorf("expected our own lock to be kept, found %v\n", err)
+	}
+
+	// a lock lockedBy cannot read, as when it is taken in between
+	other := filepath.Join(dir, "other.txt")
+	writeFile(t, lockPath(other), "")
+	b = &Buffer{Name: other, Path: other, locking: true}
+	if err := b.lock(); err == nil || b.locked {
+		t.Errorf("expected a lock of someone else not to be taken\n")
+	}
+}
+
+func hostname(t *testing.T) string {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
@@ -0,0 +1,98 @@
+package editor
+
+import "time"
+
+const (
+	MESSAGES_BUFFER    = "*Messages*"
+	MESSAGES_MAX_LINES = 1000
+)
+
+// newSpecialBuffer creates a read only buffer that does not visit a file.
+func newSpecialBuffer(parent *Editor, name string) *Buffer {
+	b := NewBuffer(parent, name, []byte(""), false)
+	b.Path = ""
+	b.Mode = TextMode
+	b.ReadOnlyMode = true
+	return b
+}
+
+// specialBuffer returns the open special buffer called name, creating it if
+// needed.
+func (e *Editor) specialBuffer(name string) *Buffer {
+	for _, b := range e.OpenBuffers {
+		if b.Name == name && b.Path == "" {
+			return b
+		}
+	}
+	return newSpecialBuffer(e, name)
+}
+
+// logMessage appends msg to the *Messages* buffer, dropping the oldest lines
+// past MESSAGES_MAX_LINES.
+func (e *Editor) logMessage(msg string) {
+	e.Messages.appendText(time.Now().Format("15:04:05") + " " + msg + "\n")
+	e.Messages.trimLines(MESSAGES_MAX_LINES)
+}
+
+// ShowMessages makes the *Messages* buffer current, opening it again if it
+// was closed.
+func (e *Editor) ShowMessages() {
+	e.showBuffer(e.Messages)
+	e.Messages.MoveEndFile()
+}
+
+// showBuffer makes b the current buffer, adding it to the open buffers if needed.
+func (e *Editor) showBuffer(b *Buffer) {
+	for i, open := range e.OpenBuffers {
+		if open == b {
+			e.CurrentBuffer = i
+			return
+		}
+	}
+	e.OpenBuffers = append(e.OpenBuffers, b)
+	e.CurrentBuffer = len(e.OpenBuffers) - 1
+}
+
+// appendText adds text at the end of the buffer without recording undo, even
+// when the buffer is read only. A cursor at the end follows the text.
+func (b *Buffer) appendText(text string) {
+	cursor := b.gapStart
+	atEnd := cursor == b.length()
+	// the text does not come from the user, the file is not locked for it
+	readOnly, markActive, locking := b.ReadOnlyMode, b.markActive, b.locking
+	b.ReadOnlyMode, b.markActive, b.locking = false, false, false
+
+	b.moveTo(b.length())
+	b.Insert(text, false)
+
+	b.ReadOnlyMode, b.markActive, b.locking = readOnly, markActive, locking
+	if !atEnd {
+		b.moveTo(cursor)
+	}
+}
+
+// setText replaces the content of a special buffer and moves to its start.
+func (b *Buffer) setText(text string) {
+	b.moveTo(0)
+	b.gapEnd = len(b.content)
+	b.markActive = false
+	b.markPos = 0
+	b.baseRow = 0
+	b.appendText(text)
+	b.moveTo(0)
+}
+
+// trimLines removes lines from the start of the buffer, without recording
+// undo, so that at most maxLines remain.
+func (b *Buffer) trimLines(maxLines int) {
+	extra := b.LineCount() - maxLines
+	if extra <= 0 {
+		return
+	}
+	end := b.rowStart(extra)
+	cursor := b.gapStart
+	b.moveTo(0)
+	b.gapEnd += end
+	b.markPos = max(b.markPos-end, 0)
+	b.moveTo(max(cursor-end, 0))
+}
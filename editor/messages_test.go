@@ -0,0 +1,37 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMessagesLog(t *testing.T) {
+	e := CreateEditor()
+	for i := 0; i < MESSAGES_MAX_LINES+10; i++ {
+		e.Minibuffer.SetMessage(fmt.Sprintf("message %d", i))
+	}
+	e.Minibuffer.ShowPrompt("Find file: ")
+
+	m := e.Messages
+	content := m.text(0, m.length())
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != MESSAGES_MAX_LINES-1 {
+		t.Errorf("expected %d lines, found %d\n", MESSAGES_MAX_LINES-1, len(lines))
+	}
+	if !strings.HasSuffix(lines[len(lines)-1], fmt.Sprintf(" message %d", MESSAGES_MAX_LINES+9)) {
+		t.Errorf("unexpected last message %q\n", lines[len(lines)-1])
+	}
+	if strings.Contains(content, "Find file") {
+		t.Errorf("prompts should not be logged\n")
+	}
+
+	m.MoveStartFile()
+	m.Insert("x", true)
+	if strings.HasPrefix(m.text(0, m.length()), "x") {
+		t.Errorf("*Messages* should be read only\n")
+	}
+	if !strings.HasSuffix(m.text(0, m.length()), " Buffer is read-only: *Messages*\n") {
+		t.Errorf("expected read only error to be logged\n")
+	}
+}
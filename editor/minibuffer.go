@@ -1,34 +1,171 @@
 package editor
 
-import "org.example.goedit/utils"
+import (
+	"strings"
+
+	"org.example.goedit/utils"
+)
 
 type Minibuffer struct {
-	message string
-	input   string
-	col     int
-	ready   chan<- bool
-	Focused bool
-	Dirty   bool
+	message    string
+	input      string
+	col        int
+	completion *completion
+	Candidates []string // completion candidates to show, if any
+	Selected   int      // index of the selected candidate, -1 to use the input as typed
+	Focused    bool
+	Dirty      bool
+	logMessage func(msg string) // called with every message, may be nil
+
+	history    []string // history of the current prompt, oldest first
+	historyPos int      // element shown, len(history) when showing the typed input
+	typed      string   // input typed before moving in the history
+
+	prompt *prompt   // prompt being read, nil when the minibuffer is not focused
+	outer  []*prompt // prompts interrupted by a recursive one, innermost last
+}
+
+// prompt is an input read in the minibuffer, with the state saved while a
+// recursive prompt is active.
+type prompt struct {
+	label      string
+	choices    string // keys answering a single key prompt, empty for a line of input
+	onConfirm  func(input string)
+	onCancel   func()
+	input      string
+	col        int
+	completion *completion
+	history    []string
+	historyPos int
+	typed      string
+}
+
+func NewMinibuffer() *Minibuffer {
+	return &Minibuffer{}
+}
+
+// Prompt reads an input in the minibuffer, starting with initial. onConfirm
+// is called with the input when it is confirmed with RET and onCancel, which
+// may be nil, when it is cancelled with C-g. Both run on the UI goroutine, so
+// they can do anything a command does, including prompting again. A prompt
+// opened while another one is active is recursive: the outer one is resumed
+// when it ends.
+func (m *Minibuffer) Prompt(label string, initial string, onConfirm func(input string), onCancel func()) {
+	m.readPrompt(label, initial, nil, nil, onConfirm, onCancel)
 }
 
-func NewMinibuffer(ready chan<- bool) *Minibuffer {
-	return &Minibuffer{
-		ready: ready,
+// Choose asks a question answered with a single key, one of the characters of
+// choices, and calls onChoice with it. Other keys repeat the question with the
+// valid answers.
+func (m *Minibuffer) Choose(label string, choices string, onChoice func(choice byte), onCancel func()) {
+	m.readPrompt(label, "", nil, nil, func(input string) {
+		onChoice(input[0])
+	}, onCancel)
+	m.prompt.choices = choices
+}
+
+// readPrompt is Prompt with c the completion used by TAB, if any, and history
+// the inputs navigated with M-p and M-n.
+func (m *Minibuffer) readPrompt(label string, initial string, c *completion, history []string, onConfirm func(input string), onCancel func()) {
+	if m.prompt != nil {
+		m.prompt.input, m.prompt.col = m.input, m.col
+		m.prompt.completion = m.completion
+		m.prompt.history, m.prompt.historyPos, m.prompt.typed = m.history, m.historyPos, m.typed
+		m.outer = append(m.outer, m.prompt)
 	}
+	m.prompt = &prompt{label: label, onConfirm: onConfirm, onCancel: onCancel}
+	m.Focused = true
+	m.completion = c
+	m.setHistory(history)
+	m.ShowPrompt(label)
+	m.SetInput(initial)
 }
 
+// endPrompt closes the current prompt and resumes the outer one, if any.
+func (m *Minibuffer) endPrompt() *prompt {
+	p := m.prompt
+	m.ConsumeInput()
+	if len(m.outer) == 0 {
+		m.prompt = nil
+		m.Focused = false
+		m.completion = nil
+		m.setHistory(nil)
+		m.ShowPrompt("")
+		return p
+	}
+	m.prompt = m.outer[len(m.outer)-1]
+	m.outer = m.outer[:len(m.outer)-1]
+	m.completion = m.prompt.completion
+	m.history, m.historyPos, m.typed = m.prompt.history, m.prompt.historyPos, m.prompt.typed
+	m.ShowPrompt(m.prompt.label)
+	m.SetInput(m.prompt.input)
+	m.col = m.prompt.col
+	return p
+}
+
+// ConfirmAction confirms the input, or the selected candidate when completing.
+// Choosing a candidate the completion descends into, like a directory, keeps
+// the prompt open.
 func (m *Minibuffer) ConfirmAction() {
-	m.ready <- true
+	if m.prompt == nil || m.prompt.choices != "" {
+		return
+	}
+	if m.completion != nil && m.Selected >= 0 && m.Selected < len(m.Candidates) {
+		m.SetInput(m.completion.choose(m.input, m.Candidates[m.Selected]))
+		if m.completion.descend != nil && m.completion.descend(m.input) {
+			return
+		}
+	}
+	input := m.input
+	p := m.endPrompt()
+	p.onConfirm(input)
 }
 
+// RejectAction cancels the current prompt.
 func (m *Minibuffer) RejectAction() {
-	m.ConsumeInput()
-	m.ready <- false
+	if m.prompt == nil {
+		return
+	}
+	p := m.endPrompt()
+	if p.onCancel != nil {
+		p.onCancel()
+	} else if m.prompt == nil {
+		m.SetMessage("Quit")
+	}
+}
+
+// answer handles a key typed at a Choose prompt.
+func (m *Minibuffer) answer(key string) {
+	p := m.prompt
+	if len(key) != 1 || !strings.Contains(p.choices, key) {
+		m.ShowPrompt("Please answer " + choicesList(p.choices) + ".  " + p.label)
+		return
+	}
+	m.endPrompt()
+	p.onConfirm(key)
+}
+
+// choicesList returns the keys of choices as "y, n or q".
+func choicesList(choices string) string {
+	keys := strings.Split(choices, "")
+	if len(keys) < 2 {
+		return choices
+	}
+	return strings.Join(keys[:len(keys)-1], ", ") + " or " + keys[len(keys)-1]
 }
 
 func (m *Minibuffer) SetMessage(msg string) {
 	m.message = msg
 	m.Dirty = true
+	if m.logMessage != nil {
+		m.logMessage(msg)
+	}
+}
+
+// ShowPrompt displays text like SetMessage but does not log it.
+func (m *Minibuffer) ShowPrompt(text string) {
+	m.message = text
+	m.Dirty = true
 }
 
 func (m *Minibuffer) GetLine() string {
@@ -40,17 +177,136 @@ func (m *Minibuffer) ConsumeInput() string {
 	res := m.input
 	m.input = ""
 	m.col = 0
+	m.Candidates = nil
 	return res
 }
 
+// SetInput replaces the input and moves the cursor to its end.
+func (m *Minibuffer) SetInput(input string) {
+	m.input = input
+	m.col = len(input)
+	m.Dirty = true
+	m.updateCandidates()
+}
+
+// setHistory sets the history navigated with HistoryPrevious and HistoryNext.
+func (m *Minibuffer) setHistory(history []string) {
+	m.history = history
+	m.historyPos = len(history)
+	m.typed = ""
+}
+
+func (m *Minibuffer) HistoryPrevious() {
+	if m.historyPos == 0 {
+		return
+	}
+	if m.historyPos == len(m.history) {
+		m.typed = m.input
+	}
+	m.historyPos -= 1
+	m.SetInput(m.history[m.historyPos])
+}
+
+func (m *Minibuffer) HistoryNext() {
+	if m.historyPos == len(m.history) {
+		return
+	}
+	m.historyPos += 1
+	if m.historyPos == len(m.history) {
+		m.SetInput(m.typed)
+	} else {
+		m.SetInput(m.history[m.historyPos])
+	}
+}
+
+// HistorySearch moves to the previous history element containing the typed input.
+func (m *Minibuffer) HistorySearch() {
+	pattern := m.input
+	if m.historyPos < len(m.history) {
+		pattern = m.typed
+	}
+	for i := m.historyPos - 1; i >= 0; i-- {
+		if strings.Contains(m.history[i], pattern) {
+			m.typed = pattern
+			m.historyPos = i
+			m.SetInput(m.history[i])
+			return
+		}
+	}
+}
+
+// Complete completes the input as far as it is unambiguous or, when that
+// does not change it, inserts the selected candidate.
+func (m *Minibuffer) Complete() {
+	if m.completion == nil {
+		return
+	}
+	if m.completion.complete != nil {
+		input, _ := m.completion.complete(m.input)
+		if input != m.input {
+			m.SetInput(input)
+			return
+		}
+	}
+	if len(m.Candidates) > 0 {
+		m.SetInput(m.completion.choose(m.input, m.Candidates[max(m.Selected, 0)]))
+	}
+}
+
+// SelectNext moves the selection down the candidates, wrapping around
+// through the typed input.
+func (m *Minibuffer) SelectNext() {
+	if len(m.Candidates) > 0 {
+		m.Selected += 1
+		if m.Selected == len(m.Candidates) {
+			m.Selected = -1
+		}
+		m.Dirty = true
+	}
+}
+
+func (m *Minibuffer) SelectPrevious() {
+	if len(m.Candidates) > 0 {
+		m.Selected -= 1
+		if m.Selected < -1 {
+			m.Selected = len(m.Candidates) - 1
+		}
+		m.Dirty = true
+	}
+}
+
+// updateCandidates filters the candidates of the completion with the input.
+func (m *Minibuffer) updateCandidates() {
+	if m.completion == nil {
+		m.Candidates = nil
+		return
+	}
+	items, pattern := m.completion.source(m.input)
+	m.Candidates = utils.FuzzyFilter(pattern, items)
+	m.Selected = -1
+	if len(m.Candidates) > 0 && m.completion.selectFirst {
+		m.Selected = 0
+	}
+}
+
 func (m *Minibuffer) GetCursor() int {
 	return len(m.message) + m.col
 }
 
 func (m *Minibuffer) InsertAtCol(str string) {
+	if m.prompt != nil && m.prompt.choices != "" {
+		m.answer(str)
+		return
+	}
 	if m.col >= 0 && m.col <= len(m.input) {
 		m.input = m.input[0:m.col] + str + m.input[m.col:]
 		m.col += 1
+		if m.completion != nil && m.completion.substitute != nil {
+			before := m.completion.substitute(m.input[0:m.col])
+			m.input = before + m.input[m.col:]
+			m.col = len(before)
+		}
+		m.updateCandidates()
 	}
 }
 
@@ -58,6 +314,7 @@ func (m *Minibuffer) DeleteAtCol() {
 	if m.col > 0 && m.col <= len(m.input) {
 		m.input = m.input[0:m.col-1] + m.input[m.col:]
 		m.col -= 1
+		m.updateCandidates()
 	}
 }
 
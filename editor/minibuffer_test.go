@@ -0,0 +1,63 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompletingSelection(t *testing.T) {
+	m := NewMinibuffer()
+	var chosen string
+	confirm := func(input string) { chosen = input }
+	candidates := listCompletion(func() []string {
+		return []string{"indent-region", "indent-rigidly", "tabify"}
+	})
+	m.readPrompt("M-x ", "", candidates, nil, confirm, nil)
+	if len(m.Candidates) != 3 || m.Selected != 0 {
+		t.Fatalf("expected all candidates with the first selected, found %v %d\n", m.Candidates, m.Selected)
+	}
+
+	m.InsertAtCol("r")
+	m.InsertAtCol("g")
+	m.SelectNext()
+	m.ConfirmAction()
+	if chosen != "indent-rigidly" {
+		t.Errorf("expected indent-rigidly to be chosen, found %q\n", chosen)
+	}
+
+	m.readPrompt("M-x ", "new", candidates, nil, confirm, nil)
+	m.SelectPrevious()
+	m.ConfirmAction()
+	if chosen != "new" {
+		t.Errorf("expected typed input, found %q\n", chosen)
+	}
+}
+
+func TestRecursivePrompt(t *testing.T) {
+	m := NewMinibuffer()
+	var result []string
+	m.Prompt("Outer: ", "", func(input string) {
+		result = append(result, "outer "+input)
+	}, nil)
+	m.InsertAtCol("a")
+
+	cancelled := false
+	m.Prompt("Inner: ", "", func(input string) {
+		result = append(result, "inner "+input)
+		m.Prompt("Chained: ", "", func(input string) {
+			result = append(result, "chained "+input)
+		}, nil)
+	}, func() { cancelled = true })
+	m.InsertAtCol("b")
+	m.ConfirmAction()
+	m.RejectAction()
+	if m.GetLine() != "Outer: a" {
+		t.Errorf("expected outer prompt to be resumed, found %q\n", m.GetLine())
+	}
+	m.ConfirmAction()
+
+	expected := []string{"inner b", "outer a"}
+	if strings.Join(result, ",") != strings.Join(expected, ",") || cancelled || m.Focused {
+		t.Errorf("expected %v, found %v\n", expected, result)
+	}
+}
@@ -0,0 +1,48 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Mode holds the language specific behaviour of a buffer.
+type Mode struct {
+	Name        string
+	Extensions  []string
+	BlockIndent bool              // indent after an opening bracket, dedent on the closing one
+	Keys        map[string]string // commands bound to single keys, like "RET", overriding the global ones
+}
+
+var TextMode = &Mode{Name: "text"}
+
+var modes = []*Mode{
+	{Name: "go", Extensions: []string{".go"}, BlockIndent: true},
+	{Name: "c", Extensions: []string{".c", ".h", ".cpp", ".hpp", ".cc"}, BlockIndent: true},
+	{Name: "java", Extensions: []string{".java", ".kt"}, BlockIndent: true},
+	{Name: "js", Extensions: []string{".js", ".ts", ".jsx", ".tsx", ".json"}, BlockIndent: true},
+	{Name: "rust", Extensions: []string{".rs"}, BlockIndent: true},
+	{Name: "shell", Extensions: []string{".sh", ".bash"}, BlockIndent: true},
+	{Name: "python", Extensions: []string{".py"}},
+	{Name: "markdown", Extensions: []string{".md"}},
+}
+
+// ModeForFile picks the mode from the file extension, defaulting to TextMode.
+func ModeForFile(path string) *Mode {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, mode := range modes {
+		for _, e := range mode.Extensions {
+			if e == ext {
+				return mode
+			}
+		}
+	}
+	return TextMode
+}
+
+func isOpener(ch byte) bool {
+	return ch == '{' || ch == '(' || ch == '['
+}
+
+func isCloser(ch byte) bool {
+	return ch == '}' || ch == ')' || ch == ']'
+}
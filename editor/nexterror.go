@@ -0,0 +1,129 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errorRegexp matches the file:line: and file:line:col: prefixes of the Go,
+// gcc and grep -n messages. Go test failures are indented.
+var errorRegexp = regexp.MustCompile(`^\s*([^\s:]+):([0-9]+):(?:([0-9]+):)?`)
+
+// location is a position in a file referred to by a line of an error list.
+type location struct {
+	row  int // row of the message in the error list buffer
+	path string
+	line int
+	col  int // 0 when unknown
+}
+
+// errorList holds the locations found in a buffer like *compilation*, which
+// next-error visits in turn.
+type errorList struct {
+	dir       string // directory of the relative paths
+	locations []location
+	current   int // index of the location visited last, -1 before the first
+	row       int // row of the next line appended, counted to not scan the buffer
+}
+
+func newErrorList(dir string) *errorList {
+	return &errorList{dir: dir, current: -1}
+}
+
+// appendLine appends a line of output at the end of b, on the row counted by
+// l, and records its location. It returns the row of the line.
+func (l *errorList) appendLine(b *Buffer, line string) int {
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	row := l.row
+	b.appendText(line)
+	l.scan(row, line)
+	l.row += 1
+	return row
+}
+
+// scan records the location of the message on row, if any.
+func (l *errorList) scan(row int, line string) {
+	match := errorRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	loc := location{row: row, path: match[1]}
+	loc.line, _ = strconv.Atoi(match[2])
+	loc.col, _ = strconv.Atoi(match[3])
+	if !filepath.IsAbs(loc.path) {
+		loc.path = filepath.Join(l.dir, loc.path)
+	}
+	l.locations = append(l.locations, loc)
+}
+
+// NextError visits the count-th next location of the last error list, or a
+// previous one when count is negative.
+func (e *Editor) NextError(count int) {
+	b := e.errorBuffer
+	if b == nil || b.errors == nil || !e.isOpen(b) {
+		e.Minibuffer.SetMessage("No error list to visit")
+		return
+	}
+	i := b.errors.current + count
+	if i < 0 {
+		e.Minibuffer.SetMessage("Moved back before first error")
+		return
+	}
+	if i >= len(b.errors.locations) {
+		e.Minibuffer.SetMessage("Moved past last error")
+		return
+	}
+	e.visitLocation(b, i)
+}
+
+// GotoError visits the location of the message at the cursor.
+func (e *Editor) GotoError() {
+	b := e.GetCurrentBuffer()
+	if b.errors == nil {
+		return
+	}
+	row := b.CurrentRow()
+	for i, loc := range b.errors.locations {
+		if loc.row == row {
+			e.visitLocation(b, i)
+			return
+		}
+	}
+	e.Minibuffer.SetMessage("No error here")
+}
+
+// visitLocation opens the file of the i-th location of b at its line and
+// column, and moves the cursor of b to its message.
+func (e *Editor) visitLocation(b *Buffer, i int) {
+	l := b.errors
+	loc := l.locations[i]
+	l.current = i
+	e.errorBuffer = b
+	b.GotoLine(loc.row)
+	if _, err := os.Stat(loc.path); err != nil {
+		e.Minibuffer.SetMessage("Cannot find " + loc.path)
+		return
+	}
+	target := e.visitFile(loc.path)
+	if target == nil {
+		return
+	}
+	target.GotoLine(loc.line - 1)
+	start := target.gapStart
+	target.moveTo(min(start+max(loc.col-1, 0), target.lineEnd(start)))
+}
+
+// isOpen tells if b is one of the open buffers.
+func (e *Editor) isOpen(b *Buffer) bool {
+	for _, open := range e.OpenBuffers {
+		if open == b {
+			return true
+		}
+	}
+	return false
+}
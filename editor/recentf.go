@@ -0,0 +1,39 @@
+package editor
+
+import "os"
+
+// addRecentFile puts path first in the list of recent files.
+func (e *Editor) addRecentFile(path string) {
+	if err := e.History.Add(HISTORY_RECENTF, absPath(path)); err != nil {
+		e.Minibuffer.SetMessage("Error saving recent files: " + err.Error())
+	}
+}
+
+// recentFiles returns the recently visited files that still exist, most
+// recent first and with ~ for the home directory. The others are dropped
+// from the list.
+func (e *Editor) recentFiles() []string {
+	items := e.History.Items(HISTORY_RECENTF)
+	files := make([]string, 0, len(items))
+	var missing []string
+	for i := len(items) - 1; i >= 0; i-- {
+		if _, err := os.Stat(items[i]); err != nil {
+			missing = append(missing, items[i])
+			continue
+		}
+		files = append(files, abbreviateFileName(items[i]))
+	}
+	for _, path := range missing {
+		if err := e.History.Remove(HISTORY_RECENTF, path); err != nil {
+			e.Minibuffer.SetMessage("Error saving recent files: " + err.Error())
+		}
+	}
+	return files
+}
+
+// RecentfOpen prompts for one of the recent files and visits it.
+func (e *Editor) RecentfOpen() {
+	e.CompletingRead("Open recent file: ", e.recentFiles, "", func(input string) {
+		e.FindFile(expandFileName(input))
+	})
+}
@@ -0,0 +1,42 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecentFiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", "/nonexistent")
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	gone := filepath.Join(dir, "gone.txt")
+	writeFile(t, a, "a\n")
+	writeFile(t, b, "b\n")
+	writeFile(t, gone, "gone\n")
+
+	e := CreateEditor()
+	e.FindFile(gone)
+	e.FindFile(b)
+	e.FindFile(a)
+	e.FindFile(b)
+	os.Remove(gone)
+
+	if files := e.recentFiles(); !reflect.DeepEqual(files, []string{b, a}) {
+		t.Errorf("expected the existing files, most recent first, found %v\n", files)
+	}
+	reloaded, _ := LoadHistory(filepath.Join(ConfigDir(), "history"))
+	if items := reloaded.Items(HISTORY_RECENTF); !reflect.DeepEqual(items, []string{a, b}) {
+		t.Errorf("expected the missing file to be pruned, found %v\n", items)
+	}
+
+	e.RecentfOpen()
+	e.Minibuffer.SelectNext()
+	e.Minibuffer.ConfirmAction()
+	if e.GetCurrentBuffer().Path != a {
+		t.Errorf("expected to visit %s, found %s\n", a, e.GetCurrentBuffer().Path)
+	}
+}
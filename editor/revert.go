@@ -0,0 +1,139 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// diskState is what is known of a file when it was last read or saved.
+type diskState struct {
+	modTime  time.Time
+	size     int64
+	hash     [sha256.Size]byte
+	declined time.Time // modification time of a version the user chose not to reload
+}
+
+func (b *Buffer) recordDiskState(info os.FileInfo, content []byte) {
+	b.disk = diskState{
+		modTime: info.ModTime(),
+		size:    int64(len(content)),
+		hash:    sha256.Sum256(content),
+	}
+}
+
+// diskChanged tells if the file of b differs from the version last read or
+// saved. Only the content counts, a file touched without changes is not.
+func (b *Buffer) diskChanged() (bool, os.FileInfo) {
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return false, nil
+	}
+	if info.ModTime().Equal(b.disk.modTime) && info.Size() == b.disk.size {
+		return false, info
+	}
+	content, err := os.ReadFile(b.Path)
+	if err != nil {
+		return false, info
+	}
+	if sha256.Sum256(content) == b.disk.hash {
+		b.disk.modTime, b.disk.size = info.ModTime(), info.Size()
+		return false, info
+	}
+	return true, info
+}
+
+// SaveFile saves b, asking first when its file was changed by another program
+// since it was read or saved.
+func (e *Editor) SaveFile(b *Buffer) {
+	e.saveBuffer(b, func() {})
+}
+
+// checkFile handles a change of path on disk: a buffer following the file
+// gets the new text, the others offer to reload it.
+func (e *Editor) checkFile(path string) {
+	i := e.findBuffer(path)
+	if i < 0 {
+		return
+	}
+	b := e.OpenBuffers[i]
+	changed, info := b.diskChanged()
+	if !changed || info.ModTime().Equal(b.disk.declined) {
+		return
+	}
+	if b.AutoRevertTail && !b.Modified {
+		e.revertTail(b, info)
+		return
+	}
+
+	question := b.Name + " changed on disk; reload it?"
+	if b.Modified {
+		question = b.Name + " changed on disk; discard your changes and reload it?"
+	}
+	b.disk.declined = info.ModTime()
+	e.YOrNP(question, func(yes bool) {
+		if yes {
+			e.RevertBuffer(b)
+		}
+	})
+}
+
+// RevertBuffer replaces the text of b with the content of its file, keeping
+// the cursor where it was. The revert can be undone.
+func (e *Editor) RevertBuffer(b *Buffer) {
+	if b.Path == "" {
+		e.Minibuffer.SetMessage("Buffer " + b.Name + " is not visiting a file")
+		return
+	}
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		e.Minibuffer.SetMessage("Error reading file: " + err.Error())
+		return
+	}
+	raw, err := os.ReadFile(b.Path)
+	if err != nil {
+		e.Minibuffer.SetMessage("Error reading file: " + err.Error())
+		return
+	}
+	content, charset := decodeCharset(raw)
+	content, lineEnding := decodeLineEndings(content)
+
+	cursor := b.gapStart
+	readOnly := b.ReadOnlyMode
+	b.ReadOnlyMode = false
+	b.replaceContent(string(content))
+	b.ReadOnlyMode = readOnly
+	b.moveTo(min(cursor, b.length()))
+
+	b.Charset, b.LineEnding = charset, lineEnding
+	b.recordDiskState(info, raw)
+	b.Modified = false
+	b.unlock()
+	if err := b.removeAutoSave(); err != nil {
+		e.Minibuffer.SetMessage("Error deleting auto-save file: " + err.Error())
+		return
+	}
+	e.Minibuffer.SetMessage("Reverted " + b.Path)
+}
+
+// revertTail appends to b what was added to its file. A file that did not
+// grow, e.g. a rotated log, or whose new text is not valid in the charset of
+// the buffer, is reverted.
+func (e *Editor) revertTail(b *Buffer, info os.FileInfo) {
+	raw, err := os.ReadFile(b.Path)
+	if err != nil || int64(len(raw)) < b.disk.size {
+		e.RevertBuffer(b)
+		return
+	}
+	added := raw[b.disk.size:]
+	if strings.HasPrefix(b.Charset, "utf-8") && !utf8.Valid(added) {
+		e.RevertBuffer(b)
+		return
+	}
+	added, _ = decodeLineEndings(decodeAs(added, b.Charset))
+	b.appendText(string(added))
+	b.recordDiskState(info, raw)
+	b.Modified = false
+}
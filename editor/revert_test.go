@@ -0,0 +1,164 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// touchLater sets the modification time of path in the future, as file
+// systems may not tell two writes in a row apart.
+func touchLater(t *testing.T, path string) {
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadChangedFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	writeFile(t, path, "package main\n")
+
+	e := CreateEditor()
+	b := e.FindFile(path)
+	e.checkFile(path)
+	if e.Minibuffer.Focused {
+		t.Fatalf("expected no question for an unchanged file\n")
+	}
+
+	writeFile(t, path, "package editor\n")
+	touchLater(t, path)
+	e.checkFile(path)
+	e.Minibuffer.InsertAtCol("y")
+	if b.text(0, b.length()) != "package editor\n" || b.Modified {
+		t.Errorf("expected the new content, found %q\n", b.text(0, b.length()))
+	}
+}
+
+func TestSaveOverChangedFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	writeFile(t, path, "package main\n")
+
+	e := CreateEditor()
+	b := e.FindFile(path)
+	b.Insert("// edited\n", true)
+	writeFile(t, path, "package other\n")
+	touchLater(t, path)
+
+	e.SaveFile(b)
+	e.Minibuffer.SetInput("no")
+	e.Minibuffer.ConfirmAction()
+	if content, _ := os.ReadFile(path); string(content) != "package other\n" {
+		t.Fatalf("expected the file to be kept, found %q\n", content)
+	}
+	e.SaveFile(b)
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	if content, _ := os.ReadFile(path); string(content) != "// edited\npackage main\n" {
+		t.Errorf("expected the buffer to be saved, found %q\n", content)
+	}
+}
+
+func TestAutoRevertTail(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "server.log")
+	writeFile(t, path, "started\n")
+
+	e := CreateEditor()
+	b := e.FindFile(path)
+	b.AutoRevertTail = true
+	b.MoveEndFile()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("request\n")
+	f.Close()
+	touchLater(t, path)
+
+	e.checkFile(path)
+	if b.text(0, b.length()) != "started\nrequest\n" || b.gapStart != b.length() || e.Minibuffer.Focused {
+		t.Errorf("expected the appended line to be followed, found %q\n", b.text(0, b.length()))
+	}
+	if _, err := os.Lstat(filepath.Join(filepath.Dir(path), ".#server.log")); err == nil || b.locked {
+		t.Errorf("expected following the file not to lock it\n")
+	}
+}
+
+func TestAutoRevertTailCharset(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	testData := []struct {
+		content  string
+		appended string
+		expected string
+	}{
+		{"caf\xe9\n", "d\xe9j\xe0\n", "café\ndéjà\n"},
+		{"\xff\xfeo\x00k\x00\n\x00", "\xe9\x00\n\x00", "ok\né\n"},
+	}
+	for _, data := range testData {
+		path := filepath.Join(t.TempDir(), "server.log")
+		writeFile(t, path, data.content)
+		e := CreateEditor()
+		b := e.FindFile(path)
+		b.AutoRevertTail = true
+
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(data.appended)
+		f.Close()
+		touchLater(t, path)
+
+		e.checkFile(path)
+		if res := b.text(0, b.length()); res != data.expected {
+			t.Errorf("%s: expected %q, found %q\n", b.Charset, data.expected, res)
+		}
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	writeFile(t, path, "package main\n")
+
+	e := CreateEditor()
+	e.FindFile(path)
+	writeFile(t, path, "package editor\n")
+	touchLater(t, path)
+
+	select {
+	case fn := <-e.Events():
+		fn()
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the change to be reported\n")
+	}
+	if !e.Minibuffer.Focused {
+		t.Errorf("expected to be asked to reload the file\n")
+	}
+}
+
+func TestUnwatchClosedFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	writeFile(t, path, "package main\n")
+
+	e := CreateEditor()
+	e.closeBuffer(e.FindFile(path))
+	writeFile(t, path, "package editor\n")
+	select {
+	case <-e.Events():
+		t.Errorf("expected no change to be reported for a closed buffer\n")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	e.FindFile(path)
+	e.Quit()
+	if e.watcher != nil {
+		t.Errorf("expected the watcher to be closed on exit\n")
+	}
+}
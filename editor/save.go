@@ -0,0 +1,142 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"org.example.goedit/utils"
+)
+
+const DIFF_BUFFER = "*Diff*"
+
+// modifiedBuffers returns the buffers visiting a file with unsaved changes.
+func (e *Editor) modifiedBuffers() []*Buffer {
+	var res []*Buffer
+	for _, b := range e.OpenBuffers {
+		if b.Modified && b.Path != "" && !b.ReadOnlyMode {
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+// SaveSomeBuffers offers to save each modified buffer in turn.
+func (e *Editor) SaveSomeBuffers() {
+	buffers := e.modifiedBuffers()
+	if len(buffers) == 0 {
+		e.Minibuffer.SetMessage("(No files need saving)")
+		return
+	}
+	e.saveSomeBuffers(buffers, func() {})
+}
+
+// SaveBuffersKillEditor offers to save the modified buffers and exits, asking
+// again when some are left unsaved.
+func (e *Editor) SaveBuffersKillEditor() {
+	e.saveSomeBuffers(e.modifiedBuffers(), func() {
+		if len(e.modifiedBuffers()) == 0 {
+			e.Quit()
+			return
+		}
+		e.YesOrNoP("Modified buffers exist; exit anyway?", func(yes bool) {
+			if yes {
+				e.Quit()
+			}
+		})
+	})
+}
+
+// saveSomeBuffers asks about the first of buffers, then about the rest, and
+// calls done at the end. y saves the buffer, n skips it, ! saves it and all
+// the others, q skips all of them and d shows the changes before asking again.
+// A failed or declined save stops without calling done.
+func (e *Editor) saveSomeBuffers(buffers []*Buffer, done func()) {
+	if len(buffers) == 0 {
+		done()
+		return
+	}
+	b := buffers[0]
+	e.Minibuffer.Choose("Save file "+b.Path+"? (y, n, !, q, d) ", "yn!qd", func(choice byte) {
+		switch choice {
+		case 'y':
+			e.saveBuffer(b, func() {
+				e.saveSomeBuffers(buffers[1:], done)
+			})
+		case 'n':
+			e.saveSomeBuffers(buffers[1:], done)
+		case '!':
+			e.saveAllBuffers(buffers, done)
+		case 'q':
+			done()
+		case 'd':
+			e.showDiff(b)
+			e.saveSomeBuffers(buffers, done)
+		}
+	}, nil)
+}
+
+// saveAllBuffers saves buffers in turn and calls done once all are saved.
+func (e *Editor) saveAllBuffers(buffers []*Buffer, done func()) {
+	if len(buffers) == 0 {
+		done()
+		return
+	}
+	e.saveBuffer(buffers[0], func() {
+		e.saveAllBuffers(buffers[1:], done)
+	})
+}
+
+// saveBuffer saves b and calls onSaved, asking first when its file was
+// changed by another program since it was read or saved. onSaved is not
+// called when the save fails or is declined.
+func (e *Editor) saveBuffer(b *Buffer, onSaved func()) {
+	save := func() {
+		if err := b.Save(); err != nil {
+			e.Minibuffer.SetMessage(err.Error())
+			return
+		}
+		onSaved()
+	}
+	if b.Path == "" || b.ReadOnlyMode {
+		save()
+		return
+	}
+	if changed, _ := b.diskChanged(); changed {
+		e.YesOrNoP(b.Name+" has changed since visited or saved; save anyway?", func(yes bool) {
+			if yes {
+				save()
+			}
+		})
+		return
+	}
+	save()
+}
+
+// showDiff shows the changes of b against its file in the *Diff* buffer.
+func (e *Editor) showDiff(b *Buffer) {
+	content, err := os.ReadFile(b.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		e.Minibuffer.SetMessage("Error reading file: " + err.Error())
+		return
+	}
+	content, _ = decodeCharset(content)
+	content, _ = decodeLineEndings(content)
+
+	diff := utils.UnifiedDiff(b.Path, b.Name, splitLines(string(content)), splitLines(b.text(0, b.length())))
+	if diff == "" {
+		diff = "No differences\n"
+	}
+
+	d := e.specialBuffer(DIFF_BUFFER)
+	d.setText(diff)
+	e.showBuffer(d)
+}
+
+// splitLines splits text in lines without a last empty line for the final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,77 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveBuffersKillEditor(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	writeFile(t, path, "one\ntwo\n")
+
+	e := CreateEditor()
+	b := e.FindFile(path)
+	b.MoveEndFile()
+	b.Insert("three\n", true)
+
+	e.SaveBuffersKillEditor()
+	e.Minibuffer.InsertAtCol("d")
+	diff := e.GetCurrentBuffer()
+	if diff.Name != DIFF_BUFFER || !strings.Contains(diff.text(0, diff.length()), "+three\n") {
+		t.Fatalf("expected the diff to be shown, found %q\n", diff.text(0, diff.length()))
+	}
+	e.Minibuffer.InsertAtCol("y")
+	content, _ := os.ReadFile(path)
+	if string(content) != "one\ntwo\nthree\n" || !e.Quitting() {
+		t.Errorf("expected the file to be saved before exiting, found %q\n", content)
+	}
+}
+
+func TestKeepUnsavedBuffers(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	writeFile(t, path, "one\n")
+
+	e := CreateEditor()
+	e.FindFile(path).Insert("zero\n", true)
+
+	e.SaveBuffersKillEditor()
+	e.Minibuffer.InsertAtCol("n")
+	e.Minibuffer.SetInput("no")
+	e.Minibuffer.ConfirmAction()
+	if e.Quitting() {
+		t.Errorf("expected to stay with unsaved buffers\n")
+	}
+	e.SaveBuffersKillEditor()
+	e.Minibuffer.InsertAtCol("q")
+	e.Minibuffer.SetInput("yes")
+	e.Minibuffer.ConfirmAction()
+	if !e.Quitting() {
+		t.Errorf("expected to exit once confirmed\n")
+	}
+}
+
+func TestExitAsksBeforeOverwritingChangedFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	writeFile(t, path, "one\n")
+
+	e := CreateEditor()
+	e.FindFile(path).Insert("zero\n", true)
+	writeFile(t, path, "changed\n")
+	touchLater(t, path)
+
+	e.SaveBuffersKillEditor()
+	e.Minibuffer.InsertAtCol("y")
+	if !strings.Contains(e.Minibuffer.GetLine(), "has changed since visited or saved") {
+		t.Fatalf("expected to be asked before overwriting, found %q\n", e.Minibuffer.GetLine())
+	}
+	e.Minibuffer.SetInput("no")
+	e.Minibuffer.ConfirmAction()
+	if content, _ := os.ReadFile(path); string(content) != "changed\n" || e.Quitting() {
+		t.Errorf("expected the file to be kept and the editor to stay, found %q\n", content)
+	}
+}
@@ -0,0 +1,193 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PLACES_SIZE is the number of files whose cursor position is remembered
+const PLACES_SIZE = 500
+
+// Places remembers the cursor position in the last files visited, so that
+// visiting one of them again starts where it was left (save-place).
+type Places struct {
+	path   string
+	files  []string // oldest first
+	points map[string]int
+}
+
+// LoadPlaces reads the places file at path. A missing file results in no
+// places.
+func LoadPlaces(path string) (*Places, error) {
+	p := &Places{
+		path:   path,
+		points: make(map[string]int),
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return p, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		point, quoted, found := strings.Cut(scanner.Text(), " ")
+		if !found {
+			continue
+		}
+		pos, err := strconv.Atoi(point)
+		file, errQuote := strconv.Unquote(quoted)
+		if err == nil && errQuote == nil {
+			p.set(file, pos)
+		}
+	}
+	return p, scanner.Err()
+}
+
+// Get returns the cursor position last seen in file.
+func (p *Places) Get(file string) (int, bool) {
+	pos, found := p.points[absPath(file)]
+	return pos, found
+}
+
+// Remember records the cursor position of b, for a buffer visiting a file.
+func (p *Places) Remember(b *Buffer) {
+	if b.Path != "" {
+		p.set(absPath(b.Path), b.gapStart)
+	}
+}
+
+func (p *Places) set(file string, pos int) {
+	if _, found := p.points[file]; found {
+		for i, f := range p.files {
+			if f == file {
+				p.files = append(p.files[:i], p.files[i+1:]...)
+				break
+			}
+		}
+	}
+	p.files = append(p.files, file)
+	p.points[file] = pos
+	if len(p.files) > PLACES_SIZE {
+		delete(p.points, p.files[0])
+		p.files = p.files[1:]
+	}
+}
+
+func (p *Places) Save() error {
+	var sb strings.Builder
+	for _, file := range p.files {
+		fmt.Fprintf(&sb, "%d %s\n", p.points[file], strconv.Quote(file))
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, []byte(sb.String()), 0644)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// sessionPath returns the session file of the working directory, named
+// after it with its slashes replaced by '!'.
+func sessionPath() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ConfigDir(), "sessions", strings.ReplaceAll(cwd, "/", "!")), nil
+}
+
+// SaveSession writes the files open in the working directory's session file,
+// with their cursor, mark and scroll position, and which one is current. The
+// cursor positions are also remembered as places.
+//
+//	buffer <point> <mark> <mark active> <first row shown> "<path>"
+//	current "<path>"
+func (e *Editor) SaveSession() error {
+	var sb strings.Builder
+	for _, b := range e.OpenBuffers {
+		if b.Path == "" {
+			continue
+		}
+		e.Places.Remember(b)
+		fmt.Fprintf(&sb, "buffer %d %d %t %d %s\n", b.gapStart, b.markPos, b.markActive, b.baseRow, strconv.Quote(absPath(b.Path)))
+	}
+	if b := e.GetCurrentBuffer(); b != nil && b.Path != "" {
+		fmt.Fprintf(&sb, "current %s\n", strconv.Quote(absPath(b.Path)))
+	}
+	if err := e.Places.Save(); err != nil {
+		return err
+	}
+
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// RestoreSession visits again the files of the working directory's session.
+// Files that no longer exist are skipped.
+func (e *Editor) RestoreSession() error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	current := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		key, args, _ := strings.Cut(line, " ")
+		switch key {
+		case "buffer":
+			var point, mark, baseRow int
+			var markActive bool
+			fields := strings.SplitN(args, " ", 5)
+			if len(fields) != 5 {
+				continue
+			}
+			file, err := strconv.Unquote(fields[4])
+			_, errScan := fmt.Sscan(strings.Join(fields[:4], " "), &point, &mark, &markActive, &baseRow)
+			if err != nil || errScan != nil {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				continue
+			}
+			b := e.FindFile(file)
+			if b == nil {
+				continue
+			}
+			b.moveTo(min(point, b.length()))
+			b.markPos = min(mark, b.length())
+			b.markActive = markActive
+			b.baseRow = min(baseRow, b.CurrentRow())
+		case "current":
+			current, _ = strconv.Unquote(args)
+		}
+	}
+	if i := e.findBuffer(current); current != "" && i >= 0 {
+		e.CurrentBuffer = i
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveRestoreSession(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	cwd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	writeFile(t, a, "first\nsecond\n")
+	writeFile(t, b, "other\n")
+
+	e := CreateEditor()
+	bufA := e.FindFile(a)
+	bufA.GotoLine(1)
+	bufA.ToggleMark()
+	bufA.MoveEndLine()
+	e.FindFile(b)
+	e.CurrentBuffer = e.findBuffer(a)
+	if err := e.SaveSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := CreateEditor()
+	if err := restored.RestoreSession(); err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.OpenBuffers) != 3 {
+		t.Fatalf("expected the two files to be open, found %d buffers\n", len(restored.OpenBuffers))
+	}
+	cur := restored.GetCurrentBuffer()
+	if cur.Path != a || cur.gapStart != len("first\nsecond") || !cur.markActive || cur.markPos != len("first\n") {
+		t.Errorf("expected %s with its cursor and mark, found %s at %d\n", a, cur.Path, cur.gapStart)
+	}
+}
+
+func TestSavePlace(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	writeFile(t, path, "one\ntwo\nthree\n")
+
+	e := CreateEditor()
+	e.FindFile(path).GotoLine(2)
+	e.KillCurrentBuffer()
+
+	b := CreateEditor().FindFile(path)
+	if b.CurrentRow() != 2 {
+		t.Errorf("expected to be back on row 2, found %d\n", b.CurrentRow())
+	}
+}
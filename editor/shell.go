@@ -0,0 +1,127 @@
+package editor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const SHELL_OUTPUT_BUFFER = "*Shell Command Output*"
+
+// shellCommand is the command run by M-! or M-|, until it exits.
+type shellCommand struct {
+	process *os.Process
+}
+
+// ShellCommand prompts for a command and runs it with sh -c in the default
+// directory. The editor keeps running while it does.
+func (e *Editor) ShellCommand() {
+	e.readMinibuffer("Shell command: ", "", nil, HISTORY_SHELL, func(command string) {
+		e.startShell(command, "", e.showShellOutput)
+	})
+}
+
+// ShellCommandOnRegion prompts for a command and pipes the region to it. With
+// replace the region is replaced by the output, as a single undoable change,
+// unless the command fails or the buffer changed while it ran.
+func (e *Editor) ShellCommandOnRegion(replace bool) {
+	b := e.GetCurrentBuffer()
+	if !b.markActive {
+		e.Minibuffer.SetMessage("The mark is not set now, so there is no region")
+		return
+	}
+	e.readMinibuffer("Shell command on region: ", "", nil, HISTORY_SHELL, func(command string) {
+		start, end := min(b.markPos, b.gapStart), max(b.markPos, b.gapStart)
+		changes := b.changes
+		e.startShell(command, b.text(start, end), func(output string, err error) {
+			if !replace || err != nil {
+				e.showShellOutput(output, err)
+				return
+			}
+			if b.changes != changes || !e.isOpen(b) {
+				e.Minibuffer.SetMessage("Buffer changed while the command ran, region not replaced")
+				return
+			}
+			if b.readOnly() {
+				return
+			}
+			b.undo.BeginGroup()
+			b.markPos, b.markActive = start, true
+			b.moveTo(end)
+			b.deleteToMark()
+			b.Insert(output, true)
+			b.undo.EndGroup()
+		})
+	})
+}
+
+// startShell runs command with sh -c in the default directory, with input on
+// its standard input, and calls onExit with its output, standard error
+// included. A running command is killed first, after confirmation.
+func (e *Editor) startShell(command string, input string, onExit func(output string, err error)) {
+	if e.shell != nil {
+		e.YOrNP("A command is running; kill it?", func(yes bool) {
+			if yes && e.shell != nil {
+				killProcess(e.shell.process)
+				e.shell = nil
+				e.startShell(command, input, onExit)
+			}
+		})
+		return
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = expandFileName(e.defaultDirectory())
+	cmd.Stdin = strings.NewReader(input)
+	s := &shellCommand{}
+	var output strings.Builder
+	err := e.startProcess(cmd, func(line string) {
+		if e.shell == s {
+			output.WriteString(line)
+		}
+	}, func(err error) {
+		if e.shell == s {
+			e.shell = nil
+			onExit(output.String(), err)
+		}
+	})
+	if err != nil {
+		onExit("", err)
+		return
+	}
+	s.process = cmd.Process
+	e.shell = s
+}
+
+// KeyboardQuit stops the running shell command, if any.
+func (e *Editor) KeyboardQuit() {
+	if e.shell != nil {
+		killProcess(e.shell.process)
+		e.shell = nil
+		e.Minibuffer.SetMessage("Quit")
+	}
+}
+
+// showShellOutput shows a single line of output in the echo area and longer
+// ones in the *Shell Command Output* buffer.
+func (e *Editor) showShellOutput(output string, err error) {
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		e.Minibuffer.SetMessage("Error running command: " + err.Error())
+		return
+	}
+	output = strings.TrimSuffix(output, "\n")
+	switch {
+	case output == "" && err == nil:
+		e.Minibuffer.SetMessage("(Shell command succeeded with no output)")
+	case output == "":
+		e.Minibuffer.SetMessage("(Shell command failed with code " + strconv.Itoa(exitErr.ExitCode()) + " and no output)")
+	case !strings.Contains(output, "\n"):
+		e.Minibuffer.SetMessage(output)
+	default:
+		b := e.specialBuffer(SHELL_OUTPUT_BUFFER)
+		b.setText(output + "\n")
+		e.showBuffer(b)
+	}
+}
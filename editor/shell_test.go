@@ -0,0 +1,88 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	e := CreateEditor()
+	e.ShellCommand()
+	e.Minibuffer.SetInput("echo hello")
+	e.Minibuffer.ConfirmAction()
+	runEvents(t, e, func() bool { return e.shell == nil })
+	if line := e.Minibuffer.GetLine(); line != "hello" {
+		t.Errorf("expected the output in the echo area, found %q\n", line)
+	}
+
+	e.ShellCommand()
+	e.Minibuffer.SetInput("printf 'a\\nb\\n'")
+	e.Minibuffer.ConfirmAction()
+	runEvents(t, e, func() bool { return e.shell == nil })
+	b := e.GetCurrentBuffer()
+	if b.Name != SHELL_OUTPUT_BUFFER || b.text(0, b.length()) != "a\nb\n" {
+		t.Errorf("expected the output buffer, found %s %q\n", b.Name, b.text(0, b.length()))
+	}
+
+	e.ShellCommand()
+	e.Minibuffer.SetInput("exit 3")
+	e.Minibuffer.ConfirmAction()
+	runEvents(t, e, func() bool { return e.shell == nil })
+	if line := e.Minibuffer.GetLine(); !strings.Contains(line, "code 3") {
+		t.Errorf("expected the exit code, found %q\n", line)
+	}
+}
+
+func TestShellCommandOnRegion(t *testing.T) {
+	b := newTestBuffer(t, "a.txt", "one\nc\nb\na\ntwo\n")
+	e := b.parent
+	e.OpenBuffers = append(e.OpenBuffers, b)
+	e.CurrentBuffer = 1
+	b.GotoLine(1)
+	b.ToggleMark()
+	b.GotoLine(4)
+
+	e.ShellCommandOnRegion(true)
+	e.Minibuffer.SetInput("sort")
+	e.Minibuffer.ConfirmAction()
+	runEvents(t, e, func() bool { return e.shell == nil })
+	if text := b.text(0, b.length()); text != "one\na\nb\nc\ntwo\n" {
+		t.Errorf("expected the region to be sorted, found %q\n", text)
+	}
+	b.Undo()
+	if text := b.text(0, b.length()); text != "one\nc\nb\na\ntwo\n" {
+		t.Errorf("expected a single undo to restore the region, found %q\n", text)
+	}
+
+	// C-u 1 M-x shell-command-on-region: a prefix argument of 1 still replaces
+	b.GotoLine(1)
+	b.ToggleMark()
+	b.GotoLine(4)
+	e.PrefixArg = true
+	e.ExecuteExtendedCommand(1)
+	e.PrefixArg = false
+	e.Minibuffer.SetInput("shell-command-on-region")
+	e.Minibuffer.ConfirmAction()
+	e.Minibuffer.SetInput("sort")
+	e.Minibuffer.ConfirmAction()
+	runEvents(t, e, func() bool { return e.shell == nil })
+	if text := b.text(0, b.length()); text != "one\na\nb\nc\ntwo\n" {
+		t.Errorf("expected the region to be replaced, found %q\n", text)
+	}
+}
+
+func TestQuitShellCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	e := CreateEditor()
+	e.ShellCommand()
+	e.Minibuffer.SetInput("sleep 30 | cat")
+	e.Minibuffer.ConfirmAction()
+	if e.shell == nil || e.Minibuffer.Focused {
+		t.Fatalf("expected the command to run without blocking the editor\n")
+	}
+	e.KeyboardQuit()
+	if e.shell != nil || e.Minibuffer.GetLine() != "Quit" {
+		t.Errorf("expected C-g to stop the command\n")
+	}
+}
@@ -18,6 +18,7 @@ type UndoEvent struct {
 	Pos        int        // anchor position where the event took place
 	NumChar    int        // number of characters
 	StoredText string     // storage for deleted text
+	Group      int        // events with the same non zero group are undone together
 }
 
 type UndoStack struct {
@@ -25,6 +26,9 @@ type UndoStack struct {
 	tail        *UndoEvent
 	currentSize int
 	size        int
+	group       int // group of the events emitted now, 0 outside of a group
+	groupDepth  int
+	lastGroup   int
 }
 
 func (ue *UndoEvent) String() string {
@@ -56,7 +60,7 @@ func NewUndo(size int) *UndoStack {
 
 func (u *UndoStack) EmitEvent(t int, pos int, text string, forceNew bool) {
 	if u.currentSize < u.size {
-		if u.currentSize > 0 && u.head != nil && !forceNew {
+		if u.currentSize > 0 && u.head != nil && !forceNew && u.head.Group == u.group {
 			// check if the last event was insert and if it was local
 			if u.head.Type == INSERT_EVENT && t == INSERT_EVENT && u.head.Pos+u.head.NumChar == pos {
 				u.head.NumChar += 1
@@ -80,6 +84,7 @@ func (u *UndoStack) EmitEvent(t int, pos int, text string, forceNew bool) {
 			Pos:        pos,
 			NumChar:    1,
 			StoredText: text,
+			Group:      u.group,
 		}
 		if u.head != nil {
 			u.head.Prev = newEvent
@@ -98,6 +103,7 @@ func (u *UndoStack) EmitEvent(t int, pos int, text string, forceNew bool) {
 			Pos:        pos,
 			NumChar:    1,
 			StoredText: text,
+			Group:      u.group,
 		}
 		u.head.Prev = newEvent
 		u.head = newEvent
@@ -105,6 +111,33 @@ func (u *UndoStack) EmitEvent(t int, pos int, text string, forceNew bool) {
 	}
 }
 
+// BeginGroup makes the following events a single undo step, until the
+// matching EndGroup. Groups can be nested, the outermost one wins.
+func (u *UndoStack) BeginGroup() {
+	if u.groupDepth == 0 {
+		u.lastGroup += 1
+		u.group = u.lastGroup
+	}
+	u.groupDepth += 1
+}
+
+func (u *UndoStack) EndGroup() {
+	if u.groupDepth > 0 {
+		u.groupDepth -= 1
+	}
+	if u.groupDepth == 0 {
+		u.group = 0
+	}
+}
+
+// HeadGroup returns the group of the event that would be undone next.
+func (u *UndoStack) HeadGroup() int {
+	if u.head == nil {
+		return 0
+	}
+	return u.head.Group
+}
+
 func (u *UndoStack) PopUndoEvent() (*UndoEvent, error) {
 	if u.currentSize == 0 {
 		return nil, errors.New("Undo stack is empty")
@@ -0,0 +1,76 @@
+package editor
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// fileWatcher reports the changes of the visited files to the UI goroutine.
+// The changes of a file are coalesced until the UI has handled them.
+type fileWatcher struct {
+	mu      sync.Mutex
+	pending map[string]bool
+	watchFuncs
+}
+
+// watchFuncs control the file watcher of the platform.
+type watchFuncs struct {
+	watch   func(path string) error
+	unwatch func(path string)
+	close   func()
+}
+
+// watchFile starts watching path for changes made by other programs.
+func (e *Editor) watchFile(path string) error {
+	if e.watcher == nil {
+		w := &fileWatcher{pending: make(map[string]bool)}
+		funcs, err := watchFiles(func(path string) {
+			e.fileChanged(w, path)
+		})
+		if err != nil {
+			return err
+		}
+		w.watchFuncs = funcs
+		e.watcher = w
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return e.watcher.watch(abs)
+}
+
+// unwatchFile stops watching path, once no buffer visits it.
+func (e *Editor) unwatchFile(path string) {
+	if e.watcher == nil || path == "" {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		e.watcher.unwatch(abs)
+	}
+}
+
+// closeWatcher stops watching all the files.
+func (e *Editor) closeWatcher() {
+	if e.watcher != nil {
+		e.watcher.close()
+		e.watcher = nil
+	}
+}
+
+// fileChanged is called from the watching goroutine.
+func (e *Editor) fileChanged(w *fileWatcher, path string) {
+	w.mu.Lock()
+	pending := w.pending[path]
+	w.pending[path] = true
+	w.mu.Unlock()
+	if pending {
+		return
+	}
+	e.Post(func() {
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+		e.checkFile(path)
+	})
+}
@@ -0,0 +1,91 @@
+package editor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const watchEvents = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_MOVED_TO |
+	syscall.IN_CREATE | syscall.IN_DELETE
+
+// watchFiles watches files with inotify and calls changed with their path
+// when they change. Directories are watched rather than files, so that files
+// replaced by a rename, as many programs save, are still followed.
+func watchFiles(changed func(path string)) (watchFuncs, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return watchFuncs{}, err
+	}
+	// a non blocking file uses the runtime poller, so Close ends a pending Read
+	f := os.NewFile(uintptr(fd), "inotify")
+	var mu sync.Mutex
+	dirs := make(map[int]string)
+	wds := make(map[string]int)
+	files := make(map[string]bool)
+
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := f.Read(buf)
+			if err != nil || n <= 0 {
+				return
+			}
+			for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+				ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				name := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+				off += syscall.SizeofInotifyEvent + int(ev.Len)
+
+				mu.Lock()
+				path := filepath.Join(dirs[int(ev.Wd)], string(bytes.TrimRight(name, "\x00")))
+				watched := files[path]
+				mu.Unlock()
+				if watched {
+					changed(path)
+				}
+			}
+		}
+	}()
+
+	return watchFuncs{
+		watch: func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if files[path] {
+				return nil
+			}
+			dir := filepath.Dir(path)
+			wd, err := syscall.InotifyAddWatch(fd, dir, watchEvents)
+			if err != nil {
+				return err
+			}
+			dirs[wd] = dir
+			wds[dir] = wd
+			files[path] = true
+			return nil
+		},
+		unwatch: func(path string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !files[path] {
+				return
+			}
+			delete(files, path)
+			dir := filepath.Dir(path)
+			for file := range files {
+				if filepath.Dir(file) == dir {
+					return
+				}
+			}
+			syscall.InotifyRmWatch(fd, uint32(wds[dir]))
+			delete(dirs, wds[dir])
+			delete(wds, dir)
+		},
+		close: func() {
+			f.Close()
+		},
+	}, nil
+}
@@ -0,0 +1,65 @@
+//go:build !linux
+
+package editor
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// WATCH_INTERVAL is the time between two checks of the watched files
+const WATCH_INTERVAL = 2 * time.Second
+
+// watchFiles polls the modification time and size of files, without inotify,
+// and calls changed with their path when they change.
+func watchFiles(changed func(path string)) (watchFuncs, error) {
+	var mu sync.Mutex
+	files := make(map[string]os.FileInfo)
+	ticker := time.NewTicker(WATCH_INTERVAL)
+	done := make(chan bool)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			mu.Lock()
+			var paths []string
+			for path, old := range files {
+				info, err := os.Stat(path)
+				if (err == nil) != (old != nil) || err == nil && (!info.ModTime().Equal(old.ModTime()) || info.Size() != old.Size()) {
+					paths = append(paths, path)
+				}
+				files[path] = info
+			}
+			mu.Unlock()
+			for _, path := range paths {
+				changed(path)
+			}
+		}
+	}()
+
+	return watchFuncs{
+		watch: func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if _, found := files[path]; !found {
+				info, _ := os.Stat(path)
+				files[path] = info
+			}
+			return nil
+		},
+		unwatch: func(path string) {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(files, path)
+		},
+		close: func() {
+			ticker.Stop()
+			close(done)
+		},
+	}, nil
+}
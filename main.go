@@ -1,16 +1,42 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"runtime/debug"
 
 	"org.example.goedit/editor"
 	"org.example.goedit/tui"
 )
 
 func main() {
+	restore := flag.Bool("restore", false, "reopen the files of the last session in this directory")
+	flag.Parse()
+
 	e := editor.CreateEditor()
+	if *restore || e.Config.RestoreSession {
+		if err := e.RestoreSession(); err != nil {
+			e.Minibuffer.SetMessage("Error restoring session: " + err.Error())
+		}
+	}
+
+	defer func() {
+		// the terminal is restored by RunApp before getting here
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "panic: %v\n\n%s\n", r, debug.Stack())
+			for _, path := range e.EmergencyAutoSave() {
+				fmt.Fprintln(os.Stderr, "Auto-saved", path)
+			}
+			os.Exit(2)
+		}
+	}()
 
 	if err := tui.RunApp(e); err != nil {
 		log.Fatal(err)
 	}
+	if err := e.SaveSession(); err != nil {
+		log.Fatal("Error saving session: ", err)
+	}
 }
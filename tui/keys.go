@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gbin/goncurses"
+)
+
+var keyNames = map[goncurses.Key]string{
+	9:                       "TAB",
+	10:                      "RET",
+	27:                      "ESC",
+	32:                      "SPC",
+	127:                     "DEL",
+	goncurses.KEY_UP:        "<up>",
+	goncurses.KEY_DOWN:      "<down>",
+	goncurses.KEY_LEFT:      "<left>",
+	goncurses.KEY_RIGHT:     "<right>",
+	goncurses.KEY_BACKSPACE: "<backspace>",
+	goncurses.KEY_ENTER:     "<enter>",
+}
+
+// KeyDescription converts a key sequence to an emacs like readable form,
+// e.g. "C-a M-f x RET". Alt combinations are written as a single M- token.
+func KeyDescription(keys []goncurses.Key) string {
+	words := make([]string, 0, len(keys))
+	for i := 0; i < len(keys); i++ {
+		if keys[i] == 27 && i+1 < len(keys) {
+			i++
+			words = append(words, "M-"+keyName(keys[i]))
+		} else {
+			words = append(words, keyName(keys[i]))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// ParseKeyDescription is the inverse of KeyDescription.
+func ParseKeyDescription(desc string) ([]goncurses.Key, error) {
+	keys := make([]goncurses.Key, 0)
+	for _, word := range strings.Fields(desc) {
+		if strings.HasPrefix(word, "M-") && len(word) > 2 {
+			keys = append(keys, 27)
+			word = word[2:]
+		}
+		key, err := parseKeyName(word)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func keyName(key goncurses.Key) string {
+	if name, ok := keyNames[key]; ok {
+		return name
+	}
+	if key > 0 && key < 32 {
+		return "C-" + strings.ToLower(string(rune(key+64)))
+	}
+	if key > 32 && key < 127 {
+		return string(rune(key))
+	}
+	return fmt.Sprintf("<%d>", key)
+}
+
+func parseKeyName(word string) (goncurses.Key, error) {
+	for key, name := range keyNames {
+		if name == word {
+			return key, nil
+		}
+	}
+	if len(word) == 1 {
+		return goncurses.Key(word[0]), nil
+	}
+	if len(word) == 3 && strings.HasPrefix(word, "C-") {
+		return Ctrl(goncurses.Key(word[2])), nil
+	}
+	if strings.HasPrefix(word, "<") && strings.HasSuffix(word, ">") {
+		if code, err := strconv.Atoi(word[1 : len(word)-1]); err == nil {
+			return goncurses.Key(code), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid key %q", word)
+}
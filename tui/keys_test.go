@@ -0,0 +1,39 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gbin/goncurses"
+)
+
+func TestKeyDescription(t *testing.T) {
+	testData := []struct {
+		keys     []goncurses.Key
+		expected string
+	}{
+		{[]goncurses.Key{Ctrl('a'), Ctrl('k')}, "C-a C-k"},
+		{[]goncurses.Key{27, 'f', 'x', 10}, "M-f x RET"},
+		{[]goncurses.Key{Ctrl('x'), '(', ' ', 127}, "C-x ( SPC DEL"},
+		{[]goncurses.Key{goncurses.KEY_DOWN, Ctrl('_')}, "<down> C-_"},
+		{[]goncurses.Key{'a', 27}, "a ESC"},
+		{[]goncurses.Key{}, ""},
+	}
+
+	for _, data := range testData {
+		desc := KeyDescription(data.keys)
+		if desc != data.expected {
+			t.Errorf("%v: expected description %q, found %q\n", data.keys, data.expected, desc)
+		}
+		keys, err := ParseKeyDescription(desc)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v\n", desc, err)
+		}
+		if KeyDescription(keys) != desc {
+			t.Errorf("%q: parsed back to %v\n", desc, keys)
+		}
+	}
+
+	if _, err := ParseKeyDescription("C-a foo"); err == nil {
+		t.Errorf("expected error for invalid key\n")
+	}
+}
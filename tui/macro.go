@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"github.com/gbin/goncurses"
+	"org.example.goedit/editor"
+)
+
+// recordKey appends key to the macro being defined, if any.
+func (ui *Tui) recordKey(key goncurses.Key) {
+	if ui.recording && key != 0 {
+		ui.macro = append(ui.macro, key)
+	}
+}
+
+func (ui *Tui) startMacro(e *editor.Editor) {
+	if ui.recording {
+		e.Minibuffer.SetMessage("Already defining kbd macro")
+		return
+	}
+	ui.recording = true
+	ui.macro = nil
+	e.Minibuffer.SetMessage("Defining kbd macro...")
+}
+
+// endMacro stops recording. The keys that ended the recording (e.g. C-x ))
+// are not part of the macro.
+func (ui *Tui) endMacro(e *editor.Editor, trailing int) {
+	if !ui.recording {
+		e.Minibuffer.SetMessage("Not defining kbd macro")
+		return
+	}
+	ui.recording = false
+	if len(ui.macro) >= trailing {
+		ui.macro = ui.macro[:len(ui.macro)-trailing]
+	}
+	ui.lastMacro = ui.macro
+	ui.macro = nil
+	e.Minibuffer.SetMessage("Keyboard macro defined")
+}
+
+// executeKeys runs keys as if they were typed, before anything else pending.
+// It returns false when one of the keys exits the editor.
+func (ui *Tui) executeKeys(e *editor.Editor, keys []goncurses.Key) bool {
+	saved := ui.pending
+	ui.pending = append([]goncurses.Key{}, keys...)
+	defer func() {
+		ui.pending = append(ui.pending, saved...)
+	}()
+
+	for ui.hasPending() {
+		if !ui.handleKey(e, ui.nextKey(), 1) || e.Quitting() {
+			return false
+		}
+	}
+	return true
+}
+
+func (ui *Tui) executeMacro(e *editor.Editor, keys []goncurses.Key, count int) bool {
+	if len(keys) == 0 {
+		e.Minibuffer.SetMessage("No kbd macro has been defined")
+		return true
+	}
+	for i := 0; i < count; i++ {
+		if !ui.executeKeys(e, keys) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyMacroToRegionLines runs the last macro once on every line of the region,
+// with the cursor at the start of the line.
+func (ui *Tui) applyMacroToRegionLines(e *editor.Editor) bool {
+	buffer := e.GetCurrentBuffer()
+	start, end, ok := buffer.RegionRows()
+	if !ok {
+		e.Minibuffer.SetMessage("The mark is not set now")
+		return true
+	}
+	if len(ui.lastMacro) == 0 {
+		e.Minibuffer.SetMessage("No kbd macro has been defined")
+		return true
+	}
+	buffer.ToggleMark()
+
+	// the macro may add or remove lines, so rows are tracked by their
+	// distance from the end of the buffer which only edits below would change
+	last := buffer.LineCount() - end
+	row := start
+	for buffer.LineCount()-row >= last {
+		fromEnd := buffer.LineCount() - row
+		buffer.GotoLine(row)
+		if !ui.executeKeys(e, ui.lastMacro) {
+			return false
+		}
+		row = buffer.LineCount() - fromEnd + 1
+	}
+	return true
+}
+
+// nameLastMacro makes the last macro available as an M-x command.
+func (ui *Tui) nameLastMacro(e *editor.Editor, count int) {
+	if len(ui.lastMacro) == 0 {
+		e.Minibuffer.SetMessage("No kbd macro has been defined")
+		return
+	}
+	keys := ui.lastMacro
+	e.ReadMinibuffer("Name for last kbd macro: ", func(name string) {
+		if name == "" {
+			return
+		}
+		ui.registerMacro(e, name, keys)
+		e.Minibuffer.SetMessage("Macro named " + name)
+	})
+}
+
+// saveMacro writes a named macro to the config file so that it is loaded in
+// later sessions.
+func (ui *Tui) saveMacro(e *editor.Editor, count int) {
+	e.ReadMinibuffer("Save kbd macro (name): ", func(name string) {
+		keys, found := ui.namedMacros[name]
+		if !found {
+			e.Minibuffer.SetMessage("No macro named " + name)
+			return
+		}
+		if err := e.Config.SetMacro(name, KeyDescription(keys)); err != nil {
+			e.Minibuffer.SetMessage("Error saving macro: " + err.Error())
+			return
+		}
+		e.Minibuffer.SetMessage("Saved macro " + name)
+	})
+}
+
+func (ui *Tui) registerMacro(e *editor.Editor, name string, keys []goncurses.Key) {
+	ui.namedMacros[name] = keys
+	e.RegisterCommand(name, func(e *editor.Editor, count int) {
+		// the keys are queued for the main loop, which handles exiting,
+		// rather than run from inside the key that invoked the command
+		for i := 0; i < count; i++ {
+			ui.queueKeys(keys)
+		}
+	})
+}
+
+// loadMacros registers the macros saved in the config file.
+func (ui *Tui) loadMacros(e *editor.Editor) {
+	for name, desc := range e.Config.Macros {
+		keys, err := ParseKeyDescription(desc)
+		if err != nil {
+			e.Minibuffer.SetMessage("Error in macro " + name + ": " + err.Error())
+			continue
+		}
+		ui.registerMacro(e, name, keys)
+	}
+}
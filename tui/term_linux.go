@@ -0,0 +1,30 @@
+package tui
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// waitInput blocks until the terminal has input to read.
+func waitInput() error {
+	for {
+		var fds syscall.FdSet
+		fds.Bits[0] = 1 // stdin
+		_, err := syscall.Select(1, &fds, nil, nil, nil)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// terminalSize returns the current size of the terminal.
+func terminalSize() (int, int, bool) {
+	var ws struct {
+		rows, cols, x, y uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, 1, syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.rows == 0 || ws.cols == 0 {
+		return 0, 0, false
+	}
+	return int(ws.rows), int(ws.cols), true
+}
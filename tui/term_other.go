@@ -0,0 +1,17 @@
+//go:build !linux
+
+package tui
+
+import "time"
+
+// waitInput cannot wait on the terminal on this system, so the main loop
+// polls it.
+func waitInput() error {
+	time.Sleep(20 * time.Millisecond)
+	return nil
+}
+
+// terminalSize is not available on this system, resizing is ignored.
+func terminalSize() (int, int, bool) {
+	return 0, 0, false
+}
@@ -2,8 +2,12 @@ package tui
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/gbin/goncurses"
 	"org.example.goedit/editor"
@@ -12,11 +16,23 @@ import (
 
 var graphical = regexp.MustCompile(`^[[:graph:][:space:]]*$`)
 
+// MAX_CANDIDATES is the height of the completion candidates list
+const MAX_CANDIDATES = 10
+
 type Tui struct {
 	bufferWindow     *goncurses.Window
 	statuslineWindow *goncurses.Window
 	minibufferWindow *goncurses.Window
-	oldBufferName    string
+	candidatesWindow *goncurses.Window // shown above the status line while completing
+	oldCandidates    string
+	oldStatusLine    string
+	pending          []goncurses.Key // keys to handle before reading the terminal
+	input            chan struct{}   // signals that the terminal has input
+	inputRead        chan struct{}   // the main loop has read the input
+	recording        bool
+	macro            []goncurses.Key // macro being defined
+	lastMacro        []goncurses.Key
+	namedMacros      map[string][]goncurses.Key
 }
 
 func RunApp(e *editor.Editor) error {
@@ -26,134 +42,420 @@ func RunApp(e *editor.Editor) error {
 	}
 	defer goncurses.End()
 
+	ui.registerCommands(e)
+	ui.loadMacros(e)
+	e.StartAutoSave()
+
 	// first render
 	ui.displayEditor(e)
 
-	ui.bufferWindow.Timeout(20)
-OUT:
+	// the terminal is only read once input is known to be there, the main
+	// loop otherwise sleeps until one of its events comes
+	ui.bufferWindow.Timeout(0)
+	go ui.watchInput()
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	defer signal.Stop(resized)
+
 	for {
-		buffer := e.GetCurrentBuffer()
-		key := ui.bufferWindow.GetChar()
-		switch key {
-		case 0:
-		case Ctrl('x'):
-			// wait 2 seconds for the next key otherwise drops the ctrl-x ctrl-<?> action
-			ui.bufferWindow.Timeout(2000)
-			secondKey := ui.bufferWindow.GetChar()
-			if secondKey != 0 {
-				switch secondKey {
-				case Ctrl('c'):
-					break OUT
-				case Ctrl('f'):
-					go e.OpenBuffer()
-				case 'k':
-					e.CloseCurrentBuffer()
-					buffer = e.GetCurrentBuffer()
-					if buffer == nil {
-						break OUT
-					}
+		select {
+		case <-ui.input:
+			running := ui.handleInput(e)
+			ui.inputRead <- struct{}{}
+			if !running {
+				return nil
+			}
+		case <-resized:
+			ui.resize(e)
+		case fn := <-e.Events():
+			fn()
+			if e.Quitting() {
+				return nil
+			}
+		}
+		ui.displayEditor(e)
+	}
+}
+
+// watchInput posts an input event whenever the terminal has input, and waits
+// for the main loop to read it before watching again.
+func (ui *Tui) watchInput() {
+	for {
+		if err := waitInput(); err != nil {
+			return
+		}
+		ui.input <- struct{}{}
+		<-ui.inputRead
+	}
+}
+
+// handleInput handles the pending keys and the keys available from the
+// terminal. It returns false when the editor should exit.
+func (ui *Tui) handleInput(e *editor.Editor) bool {
+	for {
+		key := ui.nextKey()
+		if key == 0 {
+			return true
+		}
+		if !ui.handleKey(e, key, 1) || e.Quitting() {
+			return false
+		}
+	}
+}
+
+// resize lays the windows out again for the new size of the terminal.
+func (ui *Tui) resize(e *editor.Editor) {
+	rows, cols, ok := terminalSize()
+	if !ok {
+		return
+	}
+	goncurses.ResizeTerm(rows, cols)
+	ui.bufferWindow.Resize(rows-2, cols)
+	ui.statuslineWindow.Resize(1, cols)
+	ui.statuslineWindow.MoveWindow(rows-2, 0)
+	ui.minibufferWindow.Resize(1, cols)
+	ui.minibufferWindow.MoveWindow(rows-1, 0)
+	ui.bufferWindow.Clear()
+	ui.oldStatusLine = ""
+	ui.oldCandidates = ""
+	e.Minibuffer.Dirty = true
+}
+
+// nextKey returns the first pending key if there is one, otherwise it reads
+// the next key from the terminal. Only keys typed by the user are recorded
+// in keyboard macros.
+func (ui *Tui) nextKey() goncurses.Key {
+	if len(ui.pending) > 0 {
+		key := ui.pending[0]
+		ui.pending = ui.pending[1:]
+		return key
+	}
+
+	key := ui.bufferWindow.GetChar()
+	ui.recordKey(key)
+	return key
+}
+
+// unreadKey pushes back a key so that it is returned by the next call to nextKey.
+func (ui *Tui) unreadKey(key goncurses.Key) {
+	ui.pending = append([]goncurses.Key{key}, ui.pending...)
+}
+
+// queueKeys adds keys after the pending ones.
+func (ui *Tui) queueKeys(keys []goncurses.Key) {
+	ui.pending = append(ui.pending, keys...)
+}
+
+func (ui *Tui) hasPending() bool {
+	return len(ui.pending) > 0
+}
+
+func (ui *Tui) registerCommands(e *editor.Editor) {
+	e.RegisterCommand("name-last-kbd-macro", ui.nameLastMacro)
+	e.RegisterCommand("save-kbd-macro", ui.saveMacro)
+}
+
+// handleKey runs the command bound to key count times. It returns false when
+// the editor should exit.
+func (ui *Tui) handleKey(e *editor.Editor, key goncurses.Key, count int) bool {
+	buffer := e.GetCurrentBuffer()
+	if name, found := buffer.Mode.Keys[KeyDescription([]goncurses.Key{key})]; found && !e.Minibuffer.Focused {
+		e.ExecuteCommand(name, count)
+		return true
+	}
+	switch key {
+	case 0:
+	case Ctrl('u'):
+		count, key = ui.readPrefixArg(e, key)
+		return ui.handlePrefixedKey(e, key, count)
+	case Ctrl('x'):
+		// wait 2 seconds for the next key otherwise drops the ctrl-x ctrl-<?> action
+		ui.bufferWindow.Timeout(2000)
+		secondKey := ui.nextKey()
+		if secondKey != 0 {
+			switch secondKey {
+			case Ctrl('c'):
+				e.SaveBuffersKillEditor()
+			case 's':
+				e.SaveSomeBuffers()
+			case Ctrl('f'):
+				e.OpenBuffer()
+			case 'b':
+				e.SwitchBuffer()
+			case Ctrl('s'):
+				e.SaveFile(buffer)
+			case '(':
+				ui.startMacro(e)
+			case ')':
+				ui.endMacro(e, 2)
+			case 'e':
+				if ui.recording {
+					ui.endMacro(e, 2)
+				}
+				if count < 1 {
+					count = 1
 				}
+				ui.bufferWindow.Timeout(0)
+				return ui.executeMacro(e, ui.lastMacro, count)
+			case goncurses.KEY_TAB:
+				buffer.IndentRigidly(count)
+			case Ctrl('k'):
+				if ui.nextKey() == 'r' {
+					ui.bufferWindow.Timeout(0)
+					return ui.applyMacroToRegionLines(e)
+				}
+			case 'k':
+				e.KillCurrentBuffer()
 			}
-			ui.bufferWindow.Timeout(20)
-		case Ctrl('f'), goncurses.KEY_RIGHT:
-			if e.Minibuffer.Focused {
-				e.Minibuffer.MoveForward()
-			} else {
-				buffer.MoveForward()
+		}
+		ui.bufferWindow.Timeout(0)
+	case Ctrl('f'), goncurses.KEY_RIGHT:
+		if e.Minibuffer.Focused {
+			repeat(count, e.Minibuffer.MoveForward, e.Minibuffer.MoveBack)
+		} else {
+			repeat(count, buffer.MoveForward, buffer.MoveBack)
+		}
+	case Ctrl('b'), goncurses.KEY_LEFT:
+		if e.Minibuffer.Focused {
+			repeat(count, e.Minibuffer.MoveBack, e.Minibuffer.MoveForward)
+		} else {
+			repeat(count, buffer.MoveBack, buffer.MoveForward)
+		}
+	case Ctrl('g'):
+		if e.Minibuffer.Focused {
+			e.Minibuffer.RejectAction()
+		} else {
+			if buffer.IsMarkActive() {
+				buffer.ToggleMark()
 			}
-		case Ctrl('b'), goncurses.KEY_LEFT:
+			e.KeyboardQuit()
+		}
+	case Ctrl('n'), goncurses.KEY_DOWN:
+		if e.Minibuffer.Focused {
+			repeat(count, e.Minibuffer.SelectNext, e.Minibuffer.SelectPrevious)
+		} else {
+			repeat(count, buffer.MoveDown, buffer.MoveUp)
+		}
+	case Ctrl('p'), goncurses.KEY_UP:
+		if e.Minibuffer.Focused {
+			repeat(count, e.Minibuffer.SelectPrevious, e.Minibuffer.SelectNext)
+		} else {
+			repeat(count, buffer.MoveUp, buffer.MoveDown)
+		}
+	case Ctrl('a'):
+		if e.Minibuffer.Focused {
+			e.Minibuffer.MoveStartLine()
+		} else {
+			// like emacs, a count of N moves N-1 lines first
+			repeat(count-1, buffer.MoveDown, buffer.MoveUp)
+			buffer.MoveStartLine()
+		}
+	case Ctrl('e'):
+		if e.Minibuffer.Focused {
+			e.Minibuffer.MoveEndLine()
+		} else {
+			repeat(count-1, buffer.MoveDown, buffer.MoveUp)
+			buffer.MoveEndLine()
+		}
+	case Ctrl('k'):
+		buffer.DeleteToEnd(count)
+	case Ctrl('y'):
+		repeat(count, buffer.Yank, nil)
+	case Ctrl('w'):
+		buffer.Cut()
+	case Ctrl('d'):
+		repeat(count, func() { buffer.DeleteAfter(true) }, buffer.DeleteBefore)
+	case Ctrl('_'): // C-/ sends the same code in most terminals
+		buffer.UndoTimes(count)
+	case 27: // Alt-<?>
+		secondKey := ui.nextKey()
+		switch secondKey {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '-':
+			count, key = ui.readPrefixArg(e, secondKey)
+			return ui.handlePrefixedKey(e, key, count)
+		case 'f':
 			if e.Minibuffer.Focused {
-				e.Minibuffer.MoveBack()
+				repeat(count, e.Minibuffer.MoveForwardWord, e.Minibuffer.MoveBackWord)
 			} else {
-				buffer.MoveBack()
+				repeat(count, buffer.MoveForwardWord, buffer.MoveBackWord)
 			}
-		case Ctrl('g'):
+		case 'b':
 			if e.Minibuffer.Focused {
-				e.Minibuffer.RejectAction()
+				repeat(count, e.Minibuffer.MoveBackWord, e.Minibuffer.MoveForwardWord)
 			} else {
-				if buffer.IsMarkActive() {
-					buffer.ToggleMark()
-				}
+				repeat(count, buffer.MoveBackWord, buffer.MoveForwardWord)
 			}
-		case Ctrl('n'), goncurses.KEY_DOWN:
-			buffer.MoveDown()
-		case Ctrl('p'), goncurses.KEY_UP:
-			buffer.MoveUp()
-		case Ctrl('a'):
+		case '>':
+			buffer.MoveEndFile()
+		case '<':
+			buffer.MoveStartFile()
+		case goncurses.KEY_BACKSPACE, 127: // Alt-Backspace
+			repeat(count, buffer.DeleteWordBefore, nil)
+		case ' ':
+			buffer.ToggleMark()
+		case 'w':
+			buffer.Copy()
+		case 'x':
+			e.ExecuteExtendedCommand(count)
+		case 'p':
 			if e.Minibuffer.Focused {
-				e.Minibuffer.MoveStartLine()
-			} else {
-				buffer.MoveStartLine()
+				repeat(count, e.Minibuffer.HistoryPrevious, e.Minibuffer.HistoryNext)
 			}
-		case Ctrl('e'):
+		case 'n':
 			if e.Minibuffer.Focused {
-				e.Minibuffer.MoveEndLine()
-			} else {
-				buffer.MoveEndLine()
-			}
-		case Ctrl('k'):
-			buffer.DeleteToEnd()
-		case Ctrl('y'):
-			buffer.Yank()
-		case Ctrl('w'):
-			buffer.Cut()
-		case Ctrl('d'):
-			buffer.DeleteAfter(true)
-		case Ctrl('u'):
-			buffer.Undo()
-		case 27: // Alt-<?>
-			secondKey := ui.bufferWindow.GetChar()
-			switch secondKey {
-			case 'f':
-				if e.Minibuffer.Focused {
-					e.Minibuffer.MoveForwardWord()
-				} else {
-					buffer.MoveForwardWord()
-				}
-			case 'b':
-				if e.Minibuffer.Focused {
-					e.Minibuffer.MoveBackWord()
-				} else {
-					buffer.MoveBackWord()
-				}
-			case '>':
-				buffer.MoveEndFile()
-			case '<':
-				buffer.MoveStartFile()
-			case goncurses.KEY_BACKSPACE, 127: // Alt-Backspace
-				buffer.DeleteWordBefore()
-			case ' ':
-				buffer.ToggleMark()
-			case 'w':
-				buffer.Copy()
+				repeat(count, e.Minibuffer.HistoryNext, e.Minibuffer.HistoryPrevious)
 			}
-		case goncurses.KEY_ENTER, 10:
+		case 'r':
 			if e.Minibuffer.Focused {
-				e.Minibuffer.ConfirmAction()
-			} else {
-				buffer.Insert("\n", true)
+				e.Minibuffer.HistorySearch()
+			}
+		case Ctrl('\\'): // C-M-\
+			buffer.IndentRegion()
+		case '!':
+			e.ShellCommand()
+		case '|':
+			e.ShellCommandOnRegion(e.PrefixArg)
+		case 'g':
+			ui.bufferWindow.Timeout(2000)
+			switch ui.nextKey() {
+			case 'n':
+				e.NextError(count)
+			case 'p':
+				e.NextError(-count)
 			}
-		case goncurses.KEY_BACKSPACE, 127, '\b':
+			ui.bufferWindow.Timeout(0)
+		}
+	case goncurses.KEY_ENTER, 10:
+		if e.Minibuffer.Focused {
+			e.Minibuffer.ConfirmAction()
+		} else {
+			repeat(count, buffer.Newline, nil)
+		}
+	case goncurses.KEY_BACKSPACE, 127, '\b':
+		if e.Minibuffer.Focused {
+			repeat(count, e.Minibuffer.DeleteAtCol, nil)
+		} else {
+			repeat(count, buffer.DeleteBefore, func() { buffer.DeleteAfter(true) })
+		}
+	case goncurses.KEY_TAB:
+		if e.Minibuffer.Focused {
+			e.Minibuffer.Complete()
+		} else {
+			repeat(count, buffer.InsertTab, nil)
+		}
+	default:
+		if graphical.MatchString(goncurses.KeyString(key)) {
+			str := goncurses.KeyString(key)
 			if e.Minibuffer.Focused {
-				e.Minibuffer.DeleteAtCol()
+				repeat(count, func() { e.Minibuffer.InsertAtCol(str) }, nil)
 			} else {
-				buffer.DeleteBefore()
+				repeat(count, func() { buffer.SelfInsert(str) }, nil)
 			}
-		case goncurses.KEY_TAB:
-			buffer.Insert("\t", true)
-		default:
-			if graphical.MatchString(goncurses.KeyString(key)) {
-				if e.Minibuffer.Focused {
-					e.Minibuffer.InsertAtCol(goncurses.KeyString(key))
-				} else {
-					buffer.Insert(goncurses.KeyString(key), true)
-				}
+		}
+	}
+	return true
+}
+
+// handlePrefixedKey handles key with the count of a prefix argument, which
+// the command can tell from the default count with e.PrefixArg.
+func (ui *Tui) handlePrefixedKey(e *editor.Editor, key goncurses.Key, count int) bool {
+	e.PrefixArg = true
+	defer func() { e.PrefixArg = false }()
+	return ui.handleKey(e, key, count)
+}
+
+// readPrefixArg reads a numeric prefix argument started by key, which is
+// either C-u or the digit/minus typed with Alt. It returns the count and the
+// first key that is not part of the argument.
+func (ui *Tui) readPrefixArg(e *editor.Editor, key goncurses.Key) (int, goncurses.Key) {
+	arg := newPrefixArg(key)
+	ui.bufferWindow.Timeout(-1)
+	defer ui.bufferWindow.Timeout(0)
+	for {
+		if !e.Minibuffer.Focused {
+			e.Minibuffer.ShowPrompt(arg.String())
+			ui.displayMinibuffer(e.Minibuffer)
+		}
+		next := ui.nextKey()
+		if next == 27 {
+			alt := ui.nextKey()
+			if !arg.add(alt, true) {
+				ui.unreadKey(alt)
+				return arg.count(), next
 			}
+		} else if !arg.add(next, false) {
+			return arg.count(), next
 		}
+	}
+}
 
-		ui.displayEditor(e)
+// prefixArg is a prefix argument being typed.
+// A bare C-u multiplies the count by 4, C-u C-u by 16 and so on.
+type prefixArg struct {
+	universal int
+	digits    string
+}
+
+// newPrefixArg starts a prefix argument with C-u, or a digit or minus typed
+// with Alt.
+func newPrefixArg(key goncurses.Key) prefixArg {
+	if key == Ctrl('u') {
+		return prefixArg{universal: 4}
+	}
+	return prefixArg{universal: 1, digits: string(rune(key))}
+}
+
+// add adds key, typed with Alt or not, to the argument. It returns false if
+// the key is not part of the argument.
+func (p *prefixArg) add(key goncurses.Key, alt bool) bool {
+	switch {
+	case key == Ctrl('u') && !alt && p.digits == "":
+		p.universal *= 4
+	case key >= '0' && key <= '9':
+		p.digits += string(rune(key))
+	case key == '-' && p.digits == "":
+		p.digits = "-"
+	default:
+		return false
+	}
+	return true
+}
+
+func (p prefixArg) count() int {
+	switch p.digits {
+	case "":
+		return p.universal
+	case "-":
+		return -1
+	}
+	count, err := strconv.Atoi(p.digits)
+	if err != nil {
+		return p.universal
+	}
+	return count
+}
+
+func (p prefixArg) String() string {
+	res := ""
+	for u := p.universal; u > 1; u /= 4 {
+		res += "C-u "
+	}
+	if res == "" {
+		res = "C-u "
+	}
+	return res + p.digits + "-"
+}
+
+// repeat calls fn count times. A negative count calls reverse instead, when
+// the command has a natural opposite.
+func repeat(count int, fn func(), reverse func()) {
+	if count < 0 && reverse != nil {
+		fn = reverse
+		count = -count
+	}
+	for i := 0; i < count; i++ {
+		fn()
 	}
-	return nil
 }
 
 func initTUI() (*Tui, error) {
@@ -171,9 +473,11 @@ func initTUI() (*Tui, error) {
 	goncurses.InitColor(201, 984, 945, 780)
 	//goncurses.InitColor(202, 659, 600, 518)
 	goncurses.InitColor(202, 400, 361, 329)
+	goncurses.InitColor(203, 984, 741, 184)
 	goncurses.InitPair(1, 201, 199)
 	goncurses.InitPair(2, 201, 200)
 	goncurses.InitPair(3, 202, 200)
+	goncurses.InitPair(4, 203, 200)
 
 	bufferWindow.ScrollOk(true)
 	bufferWindow.Keypad(true)
@@ -208,16 +512,71 @@ func initTUI() (*Tui, error) {
 		bufferWindow:     bufferWindow,
 		statuslineWindow: statuslineWindow,
 		minibufferWindow: minibufferWindow,
+		input:            make(chan struct{}),
+		inputRead:        make(chan struct{}),
+		namedMacros:      make(map[string][]goncurses.Key),
 	}, nil
 }
 
 func (ui *Tui) displayEditor(e *editor.Editor) {
 	buffer := e.GetCurrentBuffer()
-	if buffer != nil && !e.Minibuffer.Focused {
+	if buffer != nil && (!e.Minibuffer.Focused || len(e.Minibuffer.Candidates) == 0) {
+		// prompts without candidates keep the buffer visible, e.g. for a diff
 		ui.displayBuffer(buffer)
 		ui.displayStatusLine(buffer)
+		ui.bufferWindow.NoutRefresh()
 	}
+	ui.displayCandidates(e.Minibuffer)
 	ui.displayMinibuffer(e.Minibuffer)
+	if !e.Minibuffer.Focused {
+		// leaves the terminal cursor in the buffer
+		ui.bufferWindow.Refresh()
+	}
+}
+
+// displayCandidates shows the completion candidates in a vertical list, in a
+// window over the bottom of the buffer window, highlighting the selected one.
+func (ui *Tui) displayCandidates(m *editor.Minibuffer) {
+	if len(m.Candidates) == 0 || !m.Focused {
+		if ui.candidatesWindow != nil {
+			ui.candidatesWindow.Delete()
+			ui.candidatesWindow = nil
+			ui.oldCandidates = ""
+			ui.bufferWindow.Touch()
+			ui.bufferWindow.NoutRefresh()
+		}
+		return
+	}
+	shown := fmt.Sprint(m.Selected, m.Candidates)
+	if ui.candidatesWindow != nil && shown == ui.oldCandidates {
+		return
+	}
+	ui.oldCandidates = shown
+
+	maxRows, maxCols := ui.bufferWindow.MaxYX()
+	rows := min(len(m.Candidates), MAX_CANDIDATES, maxRows)
+	first := 0
+	if m.Selected >= rows {
+		first = m.Selected - rows + 1
+	}
+
+	if ui.candidatesWindow != nil {
+		ui.candidatesWindow.Delete()
+	}
+	win, err := goncurses.NewWindow(rows, maxCols, maxRows-rows, 0)
+	if err != nil {
+		return
+	}
+	win.SetBackground(goncurses.ColorPair(1))
+	for i := 0; i < rows; i++ {
+		if first+i == m.Selected {
+			win.AttrOn(goncurses.A_REVERSE)
+		}
+		win.MovePrint(i, 0, m.Candidates[first+i])
+		win.AttrOff(goncurses.A_REVERSE)
+	}
+	win.NoutRefresh()
+	ui.candidatesWindow = win
 }
 
 func (ui *Tui) displayBuffer(b *editor.Buffer) {
@@ -225,7 +584,7 @@ func (ui *Tui) displayBuffer(b *editor.Buffer) {
 
 	maxRows, maxCols := ui.bufferWindow.MaxYX()
 
-	data, totalRows, cursor, mark := b.GetContent(maxRows, editor.TABSIZE)
+	data, totalRows, cursor, mark := b.GetContent(maxRows, b.TabWidth)
 	lines := strings.Split(data, "\n")
 
 	digits := len(fmt.Sprint(totalRows))
@@ -239,7 +598,20 @@ func (ui *Tui) displayBuffer(b *editor.Buffer) {
 		ui.bufferWindow.MovePrintf(i, 0, "%*d ", digits, b.GetBaseRow()+i)
 		ui.bufferWindow.ColorOn(2)
 
-		for j, ch := range utils.Texp(line, editor.TABSIZE) {
+		var highlights [][2]int
+		for _, r := range b.Highlights(b.GetBaseRow() + i) {
+			if r[1] <= len(line) {
+				start, end := len(utils.Texp(line[:r[0]], b.TabWidth)), len(utils.Texp(line[:r[1]], b.TabWidth))
+				highlights = append(highlights, [2]int{start, end})
+			}
+		}
+
+		for j, ch := range utils.Texp(line, b.TabWidth) {
+			for _, h := range highlights {
+				if j >= h[0] && j < h[1] {
+					ui.bufferWindow.ColorOn(4)
+				}
+			}
 			if mark.Active {
 				//panic(fmt.Sprintf("%v\n", mark))
 				if mark.Cursor.Row < cursor.Row {
@@ -275,6 +647,7 @@ func (ui *Tui) displayBuffer(b *editor.Buffer) {
 				ui.bufferWindow.MovePrint(i, digits+1+j, string(ch))
 			}
 			ui.bufferWindow.AttrOff(goncurses.A_REVERSE)
+			ui.bufferWindow.ColorOn(2)
 		}
 
 	}
@@ -288,10 +661,15 @@ func (ui *Tui) displayBuffer(b *editor.Buffer) {
 }
 
 func (ui *Tui) displayStatusLine(b *editor.Buffer) {
-	if ui.oldBufferName != b.Name {
-		ui.oldBufferName = b.Name
+	modified := "--"
+	if b.Modified {
+		modified = "**"
+	}
+	status := fmt.Sprintf("%s %s  [%s %s]", modified, b.Name, b.Charset, strings.ToUpper(b.LineEnding))
+	if ui.oldStatusLine != status {
+		ui.oldStatusLine = status
 		ui.statuslineWindow.Erase()
-		ui.statuslineWindow.Print(b.Name)
+		ui.statuslineWindow.Print(status)
 		ui.statuslineWindow.Refresh()
 	}
 }
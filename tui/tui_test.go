@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gbin/goncurses"
+)
+
+func TestPrefixArg(t *testing.T) {
+	type key struct {
+		key goncurses.Key
+		alt bool
+	}
+	testData := []struct {
+		keys     []key // the first one starts the argument
+		expected int
+		prompt   string
+	}{
+		{[]key{{Ctrl('u'), false}}, 4, "C-u -"},
+		{[]key{{Ctrl('u'), false}, {Ctrl('u'), false}}, 16, "C-u C-u -"},
+		{[]key{{Ctrl('u'), false}, {'1', false}, {'2', false}}, 12, "C-u 12-"},
+		{[]key{{Ctrl('u'), false}, {'-', false}}, -1, "C-u --"},
+		{[]key{{'3', true}}, 3, "C-u 3-"},
+		{[]key{{'1', true}, {'0', true}}, 10, "C-u 10-"},
+		{[]key{{'-', true}}, -1, "C-u --"},
+		{[]key{{'-', true}, {'2', false}}, -2, "C-u -2-"},
+	}
+
+	for _, data := range testData {
+		arg := newPrefixArg(data.keys[0].key)
+		for _, k := range data.keys[1:] {
+			if !arg.add(k.key, k.alt) {
+				t.Errorf("%v: expected %v to be part of the argument\n", data.keys, k)
+			}
+		}
+		if count := arg.count(); count != data.expected {
+			t.Errorf("%v: expected count %d, found %d\n", data.keys, data.expected, count)
+		}
+		if prompt := arg.String(); prompt != data.prompt {
+			t.Errorf("%v: expected prompt %q, found %q\n", data.keys, data.prompt, prompt)
+		}
+	}
+
+	arg := newPrefixArg(Ctrl('u'))
+	arg.add('5', false)
+	if arg.add(Ctrl('u'), false) || arg.add('-', false) || arg.add('f', true) || arg.add(Ctrl('n'), false) {
+		t.Errorf("expected the argument to end at the first other key\n")
+	}
+	if count := arg.count(); count != 5 {
+		t.Errorf("expected count 5, found %d\n", count)
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	testData := []struct {
+		count    int
+		reverse  bool
+		expected string
+	}{
+		{1, true, "f"},
+		{3, true, "fff"},
+		{0, true, ""},
+		{-2, true, "rr"},
+		{-2, false, ""},
+	}
+
+	for _, data := range testData {
+		res := ""
+		var reverse func()
+		if data.reverse {
+			reverse = func() { res += "r" }
+		}
+		repeat(data.count, func() { res += "f" }, reverse)
+		if res != data.expected {
+			t.Errorf("%d: expected %q, found %q\n", data.count, data.expected, res)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DIFF_CONTEXT is the number of unchanged lines shown around each change
+const DIFF_CONTEXT = 3
+
+// Edit is one line of an edit script: Op is ' ' for a line kept, '-' for a
+// line removed from the old text and '+' for a line inserted from the new one.
+type Edit struct {
+	Op   byte
+	Line string
+}
+
+// DiffLines returns the shortest edit script turning a into b, using Myers'
+// algorithm on the part between the common prefix and suffix.
+func DiffLines(a []string, b []string) []Edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix += 1
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix += 1
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{' ', line})
+	}
+	return edits
+}
+
+func myers(a []string, b []string) []Edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			x := v[offset+k+1]
+			if k != -d && (k == d || v[offset+k-1] >= v[offset+k+1]) {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// walk the trace back from the end, collecting the edits in reverse
+	var reversed []Edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			reversed = append(reversed, Edit{' ', a[x]})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			reversed = append(reversed, Edit{'+', b[prevY]})
+		} else {
+			reversed = append(reversed, Edit{'-', a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]Edit, len(reversed))
+	for i, edit := range reversed {
+		edits[len(reversed)-1-i] = edit
+	}
+	return edits
+}
+
+// UnifiedDiff formats the changes from a to b as a unified diff, empty when
+// they are the same.
+func UnifiedDiff(oldName string, newName string, a []string, b []string) string {
+	edits := DiffLines(a, b)
+	var sb strings.Builder
+	for start := 0; start < len(edits); {
+		// find the next change and extend the hunk while changes are close
+		first := start
+		for first < len(edits) && edits[first].Op == ' ' {
+			first += 1
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for i := first; i < len(edits) && i <= last+2*DIFF_CONTEXT; i++ {
+			if edits[i].Op != ' ' {
+				last = i
+			}
+		}
+		from := max(first-DIFF_CONTEXT, start)
+		to := min(last+DIFF_CONTEXT+1, len(edits))
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		oldLine, newLine := 1, 1
+		for _, edit := range edits[:from] {
+			if edit.Op != '+' {
+				oldLine += 1
+			}
+			if edit.Op != '-' {
+				newLine += 1
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, edit := range edits[from:to] {
+			if edit.Op != '+' {
+				oldCount += 1
+			}
+			if edit.Op != '-' {
+				newCount += 1
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, edit := range edits[from:to] {
+			sb.WriteByte(edit.Op)
+			sb.WriteString(edit.Line)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+func hunkRange(line int, count int) string {
+	if count == 0 {
+		// an empty range names the line before it
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	testData := []struct {
+		a, b     string
+		expected string
+	}{
+		{"a b c", "a b c", "  a|  b|  c"},
+		{"a b c", "a c", "  a|- b|  c"},
+		{"a c", "a b c", "  a|+ b|  c"},
+		{"a b c a b b a", "c b a b a c", "- a|- b|  c|+ b|  a|  b|- b|  a|+ c"},
+		{"", "x", "+ x"},
+	}
+	for _, data := range testData {
+		edits := DiffLines(strings.Fields(data.a), strings.Fields(data.b))
+		res := make([]string, len(edits))
+		for i, edit := range edits {
+			res[i] = string(edit.Op) + " " + edit.Line
+		}
+		if strings.Join(res, "|") != data.expected {
+			t.Errorf("diff %q %q: expected %q, found %q\n", data.a, data.b, data.expected, strings.Join(res, "|"))
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := strings.Split("1 2 3 4 5 6 7 8 9 10 11 12 13 14 15", " ")
+	b := strings.Split("1 2 x 4 5 6 7 8 9 10 11 12 13 14", " ")
+	expected := `--- old
++++ new
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++x
+ 4
+ 5
+ 6
+@@ -12,4 +12,3 @@
+ 12
+ 13
+ 14
+-15
+`
+	if res := UnifiedDiff("old", "new", a, b); res != expected {
+		t.Errorf("expected\n%s\nfound\n%s\n", expected, res)
+	}
+	if res := UnifiedDiff("old", "new", a, a); res != "" {
+		t.Errorf("expected no diff, found %q\n", res)
+	}
+}
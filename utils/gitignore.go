@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Gitignore matches paths against the patterns of the .gitignore files of a
+// tree. Paths are relative to the root of the tree and slash separated.
+type Gitignore struct {
+	rules []gitignoreRule
+}
+
+type gitignoreRule struct {
+	base    string // directory of the .gitignore file, empty for the root
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Add adds the patterns of the .gitignore file of directory base. They only
+// apply to the paths below it.
+func (g *Gitignore) Add(base string, content string) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := gitignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// a pattern with a slash is relative to base, otherwise it matches
+		// a name at any depth
+		prefix := "(.*/)?"
+		if strings.Contains(line, "/") {
+			prefix = ""
+			line = strings.TrimPrefix(line, "/")
+		}
+		re, err := regexp.Compile("^" + prefix + globRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		g.rules = append(g.rules, rule)
+	}
+}
+
+// Ignored tells if path is ignored. The last matching pattern decides, so a
+// negated one can include again what an earlier one ignored.
+func (g *Gitignore) Ignored(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel := path
+		if rule.base != "" {
+			if !strings.HasPrefix(path, rule.base+"/") {
+				continue
+			}
+			rel = path[len(rule.base)+1:]
+		}
+		if rule.re.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globRegexp converts a gitignore glob to a regular expression: * and ?
+// do not match a slash, ** matches any number of directories.
+func globRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i += 1
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case ch == '\\' && i+1 < len(glob):
+			i += 1
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestGitignore(t *testing.T) {
+	var g Gitignore
+	g.Add("", "# comment\n*.log\n!keep.log\nbuild/\n/vendor\ndocs/**/*.tmp\n")
+	g.Add("sub", "local.txt\n/only-here\n")
+	testData := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"a.log", false, true},
+		{"deep/dir/a.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"vendor", true, true},
+		{"src/vendor", true, false},
+		{"docs/a.tmp", false, true},
+		{"docs/x/y/a.tmp", false, true},
+		{"a.tmp", false, false},
+		{"sub/local.txt", false, true},
+		{"sub/x/local.txt", false, true},
+		{"local.txt", false, false},
+		{"sub/only-here", false, true},
+		{"sub/x/only-here", false, false},
+		{"main.go", false, false},
+	}
+	for _, data := range testData {
+		if res := g.Ignored(data.path, data.isDir); res != data.expected {
+			t.Errorf("%s: expected ignored %v, found %v\n", data.path, data.expected, res)
+		}
+	}
+}
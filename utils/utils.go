@@ -1,6 +1,11 @@
 package utils
 
-import "bytes"
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"unicode"
+)
 
 func IsDelimiter(b byte) bool {
 	delimiters := []byte(" `~!@#$%^&*()-=+[{]}\\|;:'\",.<>/?\t")
@@ -73,3 +78,149 @@ func Texp(str string, tabsize int) string {
 func IsWhitespace(b byte) bool {
 	return b == ' ' || b == '\t' || b == '\n'
 }
+
+// DetectIndent guesses the indentation style of content. It returns whether
+// lines are indented with spaces and, in that case, the most common
+// indentation step. found is false when there is not enough indentation to tell.
+func DetectIndent(content []byte) (spaces bool, width int, found bool) {
+	tabLines := 0
+	spaceLines := 0
+	steps := make(map[int]int)
+	prevIndent := 0
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		indent := 0
+		for indent < len(line) && line[indent] == ' ' {
+			indent++
+		}
+		if line[0] == '\t' {
+			tabLines++
+		} else if indent >= 2 {
+			spaceLines++
+		}
+		if line[0] != '\t' {
+			if step := indent - prevIndent; step >= 2 && step <= 8 {
+				steps[step]++
+			}
+			prevIndent = indent
+		}
+	}
+
+	if tabLines == 0 && spaceLines == 0 {
+		return false, 0, false
+	}
+	if tabLines >= spaceLines {
+		return false, 0, true
+	}
+	best := 0
+	for step, n := range steps {
+		if best == 0 || n > steps[best] || (n == steps[best] && step < best) {
+			best = step
+		}
+	}
+	return true, best, best != 0
+}
+
+// Tabify replaces runs of at least two spaces ending at a tab stop with tabs.
+func Tabify(str string, tabsize int) string {
+	exp := []byte(Texp(str, tabsize))
+	res := make([]byte, 0, len(exp))
+	start := 0
+	for ; start+tabsize <= len(exp); start += tabsize {
+		segment := exp[start : start+tabsize]
+		trimmed := bytes.TrimRight(segment, " ")
+		if len(segment)-len(trimmed) >= 2 {
+			res = append(res, trimmed...)
+			res = append(res, '\t')
+		} else {
+			res = append(res, segment...)
+		}
+	}
+	return string(append(res, exp[start:]...))
+}
+
+// FuzzyScore matches pattern as a subsequence of str, ignoring case. Higher
+// scores are better: consecutive characters and characters at the start of
+// str or of a word score more. Every position of the first character is
+// tried and the best score is kept.
+func FuzzyScore(pattern string, str string) (int, bool) {
+	// runes keep a single index space: lowercasing can change the length in bytes
+	p := []rune(strings.ToLower(pattern))
+	orig := []rune(str)
+	s := make([]rune, len(orig))
+	for i, r := range orig {
+		s[i] = unicode.ToLower(r)
+	}
+	if len(p) == 0 {
+		return 0, true
+	}
+	best := -1
+	for start := indexRune(s, p[0], 0); start >= 0; start = indexRune(s, p[0], start+1) {
+		score := 0
+		pi := 0
+		prev := -2
+		for si := start; si < len(s) && pi < len(p); si++ {
+			if s[si] != p[pi] {
+				continue
+			}
+			score += 1
+			if si == prev+1 {
+				score += 5
+			}
+			if si == 0 {
+				score += 10
+			} else if (orig[si-1] < 128 && IsDelimiter(byte(orig[si-1]))) || (unicode.IsLower(orig[si-1]) && unicode.IsUpper(orig[si])) {
+				score += 8
+			}
+			prev = si
+			pi++
+		}
+		if pi < len(p) {
+			break
+		}
+		best = max(best, score)
+	}
+	return best, best >= 0
+}
+
+// indexRune returns the index of the first r in s at or after from, or -1.
+func indexRune(s []rune, r rune, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// FuzzyFilter returns the items matching pattern, best matches first. Items
+// with the same score keep their order, shorter ones first. An empty pattern
+// keeps all the items in their order.
+func FuzzyFilter(pattern string, items []string) []string {
+	if pattern == "" {
+		return append([]string{}, items...)
+	}
+	type match struct {
+		item  string
+		score int
+	}
+	matches := make([]match, 0, len(items))
+	for _, item := range items {
+		if score, ok := FuzzyScore(pattern, item); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].item) < len(matches[j].item)
+	})
+	res := make([]string, len(matches))
+	for i, m := range matches {
+		res[i] = m.item
+	}
+	return res
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestTlen(t *testing.T) {
 	testData := []struct {
@@ -59,3 +62,97 @@ func TestTexp(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectIndent(t *testing.T) {
+	testData := []struct {
+		content string
+		spaces  bool
+		width   int
+		found   bool
+	}{
+		{"func f() {\n\treturn\n}\n", false, 0, true},
+		{"a:\n    b:\n        c\n    d\n", true, 4, true},
+		{"{\n  \"a\": {\n    \"b\": 1\n  }\n}\n", true, 2, true},
+		{"no\nindentation\n", false, 0, false},
+		{"\tmostly\n\ttabs\n    one\n", false, 0, true},
+		{"", false, 0, false},
+	}
+
+	for _, data := range testData {
+		spaces, width, found := DetectIndent([]byte(data.content))
+		if spaces != data.spaces || width != data.width || found != data.found {
+			t.Errorf("%q: expected (%v, %d, %v), found (%v, %d, %v)\n", data.content,
+				data.spaces, data.width, data.found, spaces, width, found)
+		}
+	}
+}
+
+func TestTabify(t *testing.T) {
+	testData := []struct {
+		str      string
+		tabsize  int
+		expected string
+	}{
+		{"        Hello", 8, "\tHello"},
+		{"                Hi", 8, "\t\tHi"},
+		{"Hello   Good", 8, "Hello\tGood"},
+		{"a b", 8, "a b"},
+		{"1234567 x", 8, "1234567 x"},
+		{"\t  x", 4, "\t  x"},
+		{"      x", 4, "\t  x"},
+		{"", 4, ""},
+	}
+
+	for _, data := range testData {
+		res := Tabify(data.str, data.tabsize)
+		if res != data.expected {
+			t.Errorf("%q: expected %q, found %q\n", data.str, data.expected, res)
+		}
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	testData := []struct {
+		pattern  string
+		items    []string
+		expected []string
+	}{
+		{"", []string{"b", "a"}, []string{"b", "a"}},
+		{"", []string{"longer", "a"}, []string{"longer", "a"}},
+		{"ir", []string{"indent-region", "undo", "indent-rigidly", "tabify"}, []string{"indent-region", "indent-rigidly"}},
+		{"tab", []string{"untabify", "tabify", "set-tab-width"}, []string{"tabify", "set-tab-width", "untabify"}},
+		{"bgo", []string{"buffer.go", "b.go", "main.go"}, []string{"b.go", "buffer.go"}},
+		{"XYZ", []string{"abc"}, []string{}},
+		{"rf", []string{"rfoo", "readFile"}, []string{"readFile", "rfoo"}},
+	}
+
+	for _, data := range testData {
+		res := FuzzyFilter(data.pattern, data.items)
+		if !reflect.DeepEqual(res, data.expected) {
+			t.Errorf("%q: expected %v, found %v\n", data.pattern, data.expected, res)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	testData := []struct {
+		pattern  string
+		str      string
+		score    int
+		expected bool
+	}{
+		{"", "abc", 0, true},
+		{"abc", "abc", 23, true},
+		{"ac", "a-c", 20, true},
+		{"rf", "readFile", 20, true},
+		{"x", "ȺȺȺȺx", 1, true},
+		{"ȿx", "aⱾx", 15, true},
+		{"éa", "abc", -1, false},
+	}
+	for _, data := range testData {
+		score, ok := FuzzyScore(data.pattern, data.str)
+		if score != data.score || ok != data.expected {
+			t.Errorf("%q in %q: expected %d %v, found %d %v\n", data.pattern, data.str, data.score, data.expected, score, ok)
+		}
+	}
+}